@@ -3,21 +3,36 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"ticket_goroutine/internal/config"
+	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/handler"
 	handlerImplement "ticket_goroutine/internal/handler/impl"
 	"ticket_goroutine/internal/middleware"
+	"ticket_goroutine/internal/provider/auth"
 	"ticket_goroutine/internal/provider/db"
+	"ticket_goroutine/internal/provider/health"
+	"ticket_goroutine/internal/provider/lifecycle"
+	"ticket_goroutine/internal/provider/logfile"
+	"ticket_goroutine/internal/provider/metrics"
+	"ticket_goroutine/internal/provider/tracing"
+	"ticket_goroutine/internal/provider/waitingroom"
+	"ticket_goroutine/internal/provider/worker"
 	"ticket_goroutine/internal/repository"
 	repoImplement "ticket_goroutine/internal/repository/impl_db"
+	repoSqlite "ticket_goroutine/internal/repository/impl_sqlite"
 	"ticket_goroutine/internal/usecase"
 	useCaseImplement "ticket_goroutine/internal/usecase/impl"
+	"ticket_goroutine/utils"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
@@ -25,129 +40,450 @@ var eventRepo repository.EventRepositoryInterface
 var userRepo repository.UserRepositoryInterface
 var ticketRepo repository.TicketRepositoryInterface
 var ticketOrderRepo repository.TicketOrderRepositoryInterface
+var apiKeyRepo repository.APIKeyRepositoryInterface
 
 var eventUseCase usecase.EventUseCaseInterface
 var userUseCase usecase.UserUseCaseInterface
 var ticketUseCase usecase.TicketUseCaseInterface
 var ticketOrderUseCase usecase.TicketOrderUseCaseInterface
+var authUseCase usecase.AuthUseCaseInterface
+var apiKeyUseCase usecase.APIKeyUseCaseInterface
 
 var eventHandler handler.EventHandlerInterface
 var userHandler handler.UserHandlerInterface
 var ticketHandler handler.TicketHandlerInterface
 var ticketOrderHandler handler.TicketOrderHandlerInterface
+var databaseHandler handler.DatabaseHandlerInterface
+var authHandler handler.AuthHandlerInterface
+var apiKeyHandler handler.APIKeyHandlerInterface
+var waitingRoomHandler handler.WaitingRoomHandlerInterface
+var healthHandler handler.HealthHandlerInterface
 
+var tokenIssuer *auth.TokenIssuer
+var waitingRoom *waitingroom.WaitingRoom
+var orderPool *worker.Pool
+var accessLogFile *logfile.RotatingFile
+var healthChecker *health.Checker
+
+var cfg config.Config
 var database *sql.DB
 
 func initDB() *sql.DB {
-	db, err := db.NewConnection("postgresql").GetConnection("postgres", "root", "localhost", "5432", "phincon_golang_ticket")
+	pool := db.PoolConfig{
+		MaxOpenConns:    cfg.DB.MaxOpenConns,
+		MaxIdleConns:    cfg.DB.MaxIdleConns,
+		ConnMaxLifetime: cfg.DB.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DB.ConnMaxIdleTime,
+		PingAttempts:    cfg.DB.PingAttempts,
+		PingBackoff:     cfg.DB.PingBackoff,
+		PingTimeout:     cfg.DB.PingTimeout,
+	}
+
+	db, err := db.NewConnection(cfg.DB.Driver).WithPool(pool).GetConnection(cfg.DB.Username, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.Name)
 
 	if err != nil {
-		return nil
+		log.Fatal().Msg(fmt.Sprintf("Database connection error: %s", err.Error()))
 	}
 
 	return db
 }
 
-func initEventHandler() handler.EventHandlerInterface {
+func initRepositories() {
+	if cfg.DB.Driver == "sqlite" {
+		eventRepo = repoSqlite.NewEventRepository(database)
+		userRepo = repoSqlite.NewUserRepository(database)
+		ticketRepo = repoSqlite.NewTicketRepository(database)
+		ticketOrderRepo = repoSqlite.NewTicketOrderRepository(database)
+		apiKeyRepo = repoSqlite.NewAPIKeyRepository(database)
+		return
+	}
+
 	eventRepo = repoImplement.NewEventRepository(database)
+	userRepo = repoImplement.NewUserRepository(database)
+	ticketRepo = repoImplement.NewTicketRepository(database)
+	ticketOrderRepo = repoImplement.NewTicketOrderRepository(database)
+	apiKeyRepo = repoImplement.NewAPIKeyRepository(database)
+}
 
+func initEventHandler() handler.EventHandlerInterface {
 	if eventRepo == nil {
 		log.Fatal().Msg("Event repo didn't get initialized")
 	}
-	eventUseCase = useCaseImplement.NewEventUseCase(eventRepo)
-	handler := handlerImplement.NewEventHandler(eventUseCase)
+	eventUseCase = useCaseImplement.NewEventUseCase(eventRepo, ticketOrderRepo)
+	handler := handlerImplement.NewEventHandler(eventUseCase, cfg.HTTP.ChangeTimeout)
 	return handler
 }
 
 func initUserHandler() handler.UserHandlerInterface {
-	userRepo = repoImplement.NewUserRepository(database)
-	userUseCase = useCaseImplement.NewUserUseCase(userRepo)
-	handler := handlerImplement.NewUserHandler(userUseCase)
+	userUseCase = useCaseImplement.NewUserUseCase(userRepo, ticketOrderRepo)
+	handler := handlerImplement.NewUserHandler(userUseCase, cfg.HTTP.ChangeTimeout)
 	return handler
 }
 
 func initTicketHandler() handler.TicketHandlerInterface {
-	ticketRepo = repoImplement.NewTicketRepository(database)
-
 	if eventUseCase == nil {
         log.Fatal().Msg("Event use case is not initialized")
     }
 
-	ticketUseCase = useCaseImplement.NewTicketUseCase(ticketRepo, eventUseCase)
+	ticketUseCase = useCaseImplement.NewTicketUseCase(ticketRepo, eventUseCase, ticketOrderRepo)
 
-	handler := handlerImplement.NewTicketHandler(ticketUseCase)
+	handler := handlerImplement.NewTicketHandler(ticketUseCase, cfg.HTTP.ChangeTimeout)
 	return handler
 }
 
 func initTicketOrderHandler() handler.TicketOrderHandlerInterface {
-	ticketOrderRepo = repoImplement.NewTicketOrderRepository(database)
 	ticketOrderUseCase = useCaseImplement.NewTicketOrderUseCase(ticketOrderRepo, ticketUseCase, eventUseCase, userUseCase)
-	handler := handlerImplement.NewTicketOrderHandler(ticketOrderUseCase)
+	orderPool = worker.NewPool("ticket orders", cfg.Orders.Workers, cfg.Orders.QueueSize)
+	intake := useCaseImplement.NewTicketOrderIntake(orderPool, ticketOrderUseCase, cfg.Orders.QueueWait, cfg.Orders.JobTimeout, cfg.Orders.ResultTTL)
+	handler := handlerImplement.NewTicketOrderHandler(ticketOrderUseCase, intake, cfg.HTTP.ChangeTimeout)
 	
 	return handler
 }
 
+func initAuthHandler() handler.AuthHandlerInterface {
+	tokenIssuer = auth.NewTokenIssuer(cfg.Auth.Secret, cfg.Auth.Issuer, cfg.Auth.AccessTTL, cfg.Auth.RefreshTTL)
+	authUseCase = useCaseImplement.NewAuthUseCase(userRepo, tokenIssuer)
+	handler := handlerImplement.NewAuthHandler(authUseCase)
+	return handler
+}
+
+func initAPIKeyHandler() handler.APIKeyHandlerInterface {
+	apiKeyUseCase = useCaseImplement.NewAPIKeyUseCase(apiKeyRepo, userRepo)
+	handler := handlerImplement.NewAPIKeyHandler(apiKeyUseCase, cfg.HTTP.ChangeTimeout)
+	return handler
+}
+
+func initWaitingRoomHandler() handler.WaitingRoomHandlerInterface {
+	waitingRoom = waitingroom.NewWaitingRoom(waitingroom.Config{
+		AdmitBatch:  cfg.WaitingRoom.AdmitBatch,
+		AdmitEvery:  cfg.WaitingRoom.AdmitEvery,
+		AdmitTTL:    cfg.WaitingRoom.AdmitTTL,
+		IdleTimeout: cfg.WaitingRoom.IdleTimeout,
+	})
+	waitingRoomUseCase := useCaseImplement.NewWaitingRoomUseCase(waitingRoom)
+	handler := handlerImplement.NewWaitingRoomHandler(waitingRoomUseCase)
+	return handler
+}
+
+func initHealthHandler() handler.HealthHandlerInterface {
+	migrator := newMigrator()
+	healthChecker = health.NewChecker(cfg.HTTP.HealthCheckTimeout)
+
+	healthChecker.Add("database", func(ctx context.Context) error {
+		return database.PingContext(ctx)
+	})
+	healthChecker.Add("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if pending > 0 {
+			return fmt.Errorf("%d migrations not applied", pending)
+		}
+
+		return nil
+	})
+	healthChecker.Add("ticket_order_workers", func(ctx context.Context) error {
+		if !orderPool.Running() {
+			return errors.New("worker pool is stopped")
+		}
+
+		return nil
+	})
+
+	handler := handlerImplement.NewHealthHandler(healthChecker)
+	return handler
+}
+
+func initAccessLogger() zerolog.Logger {
+	var out io.Writer = os.Stdout
+
+	if cfg.AccessLog.File != "" {
+		file, err := logfile.Open(cfg.AccessLog.File, int64(cfg.AccessLog.MaxSizeMB)<<20, cfg.AccessLog.MaxBackups)
+
+		if err != nil {
+			log.Fatal().Msg(fmt.Sprintf("Access log error: %s", err.Error()))
+		}
+
+		accessLogFile = file
+		out = file
+	}
+
+	if cfg.AccessLog.Format == "console" {
+		out = zerolog.ConsoleWriter{Out: out, NoColor: accessLogFile != nil, TimeFormat: time.RFC3339}
+	}
+
+	return zerolog.New(out).With().Timestamp().Logger()
+}
+
 func init() {
-	database = initDB()
+	// code running outside a request, like background jobs, logs through the global logger with zerolog.Ctx too
+	zerolog.DefaultContextLogger = &log.Logger
+
+	cfg = config.Load()
+}
+
+// initService sets up everything the server needs, the subcommands only use the database
+func initService() {
+	if cfg.DB.AutoMigrate {
+		autoMigrate()
+	}
+
+	initRepositories()
 	eventHandler = initEventHandler()
 	userHandler = initUserHandler()
 	ticketHandler = initTicketHandler()
 	ticketOrderHandler = initTicketOrderHandler()
+	authHandler = initAuthHandler()
+	apiKeyHandler = initAPIKeyHandler()
+
+	if cfg.WaitingRoom.Enabled {
+		waitingRoomHandler = initWaitingRoomHandler()
+	}
+
+	databaseHandler = handlerImplement.NewDatabaseHandler(database)
+	healthHandler = initHealthHandler()
+	initMetrics()
+}
+
+func initMetrics() {
+	metrics.RegisterDB(database, cfg.DB.Name)
+	metrics.RegisterGauge("ticket_order_queue_length", "Ticket orders waiting for a worker.", func() float64 {
+		return float64(orderPool.Queued())
+	})
+	metrics.RegisterCounter("panics_recovered_total", "Panics recovered in handlers and background goroutines.", func() float64 {
+		return float64(utils.PanicCount())
+	})
 }
 
 func main() {
-	router := middleware.NewRouter()
+	database = initDB()
+
+	// subcommands run before the service is set up, so "migrate status" sees the schema as it was
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		database.Close()
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "grant-role" {
+		initRepositories()
+		runGrantRole(os.Args[2:])
+		database.Close()
+		return
+	}
+
+	initService()
+
+	router := middleware.NewRouter(cfg.HTTP.RequestTimeout)
+
+	guard := middleware.NewGuard(tokenIssuer, apiKeyUseCase, handlerImplement.RespondError)
+	organizers := guard.Allow(domain.RoleAdmin, domain.RoleOrganizer)
+	selfOrAdmin := guard.SelfOr("id", domain.RoleAdmin)
+
+	// limits are per client and shared by the routes given the same name
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), handlerImplement.RespondError)
+	orderLimit := limiter.Limit("ticket-order-write", middleware.Limit{Requests: 10, Per: time.Minute})
+	loginLimit := limiter.Limit("auth", middleware.Limit{Requests: 5, Per: time.Minute})
+	signupLimit := limiter.Limit("user-signup", middleware.Limit{Requests: 5, Per: time.Hour})
+
+	router.AddRoute("GET", "/healthz", healthHandler.Live)
+	router.AddRoute("GET", "/readyz", healthHandler.Ready)
+
+	// public reads authenticate optionally, for admins to ask for soft deleted rows with include_deleted=true
+	router.AddRoute("GET", "/event/", eventHandler.GetAll, guard.Optional())
+	router.AddRoute("GET", "/event/{id}", eventHandler.FindById, guard.Optional())
+	router.AddRoute("POST", "/event/", eventHandler.Save, organizers)
+	router.AddRoute("PUT", "/event/{id}", eventHandler.Update, organizers)
+	router.AddRoute("PATCH", "/event/{id}", eventHandler.Patch, organizers)
+	router.AddRoute("DELETE", "/event/{id}", eventHandler.Delete, organizers)
+
+	router.AddRoute("GET", "/user/", userHandler.GetAll, guard.Allow(domain.RoleAdmin))
+	router.AddRoute("GET", "/user/{id}", userHandler.FindById, selfOrAdmin)
+	router.AddRoute("POST", "/user/", userHandler.Save, guard.Optional(), signupLimit)
+	router.AddRoute("PUT", "/user/{id}", userHandler.Update, selfOrAdmin)
+	router.AddRoute("PATCH", "/user/{id}", userHandler.Patch, selfOrAdmin)
+	router.AddRoute("DELETE", "/user/{id}", userHandler.Delete, selfOrAdmin)
 
-	router.AddRoute("GET", "/event/", eventHandler.GetAll)
-	router.AddRoute("GET", "/event/{id}", eventHandler.FindById)
-	router.AddRoute("POST", "/event/", eventHandler.Save)
+	router.AddRoute("GET", "/ticket/", ticketHandler.GetAll, guard.Optional(), middleware.Timeout(cfg.HTTP.ListTimeout))
+	router.AddRoute("GET", "/ticket/{id}", ticketHandler.FindById, guard.Optional())
+	router.AddRoute("POST", "/ticket/", ticketHandler.Save, organizers)
+	router.AddRoute("PUT", "/ticket/{id}", ticketHandler.Update, organizers)
+	router.AddRoute("PATCH", "/ticket/{id}", ticketHandler.Patch, organizers)
+	router.AddRoute("DELETE", "/ticket/{id}", ticketHandler.Delete, organizers)
 
-	router.AddRoute("GET", "/user/", userHandler.GetAll)
-	router.AddRoute("GET", "/user/{id}", userHandler.FindById)
-	router.AddRoute("POST", "/user/", userHandler.Save)
+	router.AddRoute("GET", "/organizer/{id}/event/", eventHandler.GetByOrganizer, guard.SelfOr("id", domain.RoleAdmin))
+	router.AddRoute("GET", "/organizer/{id}/ticket-order/", ticketOrderHandler.GetByOrganizer, guard.SelfOr("id", domain.RoleAdmin))
 
-	router.AddRoute("GET", "/ticket/", ticketHandler.GetAll)
-	router.AddRoute("GET", "/ticket/{id}", ticketHandler.FindById)
-	router.AddRoute("POST", "/ticket/", ticketHandler.Save)
+	ordersRead := guard.AllowKey(domain.ScopeOrdersRead, domain.Roles...)
+	ordersWrite := guard.AllowKey(domain.ScopeOrdersWrite, domain.RoleAdmin, domain.RoleCustomer)
 
-	router.AddRoute("GET", "/ticket-order/", ticketOrderHandler.GetAll)
-	router.AddRoute("GET", "/ticket-order/{id}", ticketOrderHandler.FindById)
-	router.AddRoute("POST", "/ticket-order/", ticketOrderHandler.Save)
+	router.AddRoute("GET", "/ticket-order/", ticketOrderHandler.GetAll, ordersRead)
+	router.AddRoute("GET", "/ticket-order/{id}", ticketOrderHandler.FindById, ordersRead)
+	router.AddRoute("GET", "/ticket-order/request/{id}", ticketOrderHandler.GetRequest, ordersRead)
+	// placing an order waits for a worker first, then for the job, which has its own timeout
+	buyTimeout := middleware.Timeout(cfg.Orders.QueueWait + cfg.Orders.JobTimeout)
+	buying := middleware.CreateStack(ordersWrite, orderLimit, buyTimeout)
+
+	// streams are ended once the server shuts down, instead of holding it up until the clients leave
+	streaming, stopStreaming := context.WithCancel(context.Background())
+
+	// during big on-sales buyers queue first and are let through at a rate the database can take
+	if waitingRoom != nil {
+		buying = middleware.CreateStack(ordersWrite, orderLimit, middleware.RequireAdmission(waitingRoom, handlerImplement.RespondError), buyTimeout)
+
+		router.AddRoute("POST", "/waiting-room/", waitingRoomHandler.Join, ordersWrite)
+		router.AddRoute("GET", "/waiting-room/{token}", waitingRoomHandler.Position, ordersWrite)
+		router.AddRoute("GET", "/waiting-room/{token}/stream", waitingRoomHandler.Stream, ordersWrite, middleware.Timeout(0), middleware.CancelOn(streaming))
+	}
+
+	router.AddRoute("POST", "/ticket-order/", ticketOrderHandler.Save, buying)
+	router.AddRoute("PUT", "/ticket-order/{id}", ticketOrderHandler.Update, ordersWrite, orderLimit)
+	router.AddRoute("PATCH", "/ticket-order/{id}", ticketOrderHandler.Patch, ordersWrite, orderLimit)
+	router.AddRoute("DELETE", "/ticket-order/{id}", ticketOrderHandler.Delete, ordersWrite, orderLimit)
+
+	router.AddRoute("POST", "/auth/login", authHandler.Login, loginLimit)
+	router.AddRoute("POST", "/auth/refresh", authHandler.Refresh, loginLimit)
+
+	admins := guard.Allow(domain.RoleAdmin)
+
+	router.AddRoute("GET", "/api-key/", apiKeyHandler.GetAll, admins)
+	router.AddRoute("GET", "/api-key/{id}", apiKeyHandler.FindById, admins)
+	router.AddRoute("GET", "/api-key/{id}/usage", apiKeyHandler.GetUsage, admins)
+	router.AddRoute("POST", "/api-key/", apiKeyHandler.Save, admins)
+	router.AddRoute("DELETE", "/api-key/{id}", apiKeyHandler.Delete, admins)
+
+	router.AddRoute("GET", "/debug/db/stats", databaseHandler.Stats, guard.Allow(domain.RoleAdmin))
 
 	middlewares := middleware.CreateStack(
-		middleware.Logging,
+		middleware.RequestID,
+		middleware.Trace,
+		middleware.Metrics,
+		middleware.Recover(handlerImplement.RespondError),
 	)
-	
+
+	if cfg.AccessLog.Enabled {
+		middlewares = middleware.CreateStack(
+			middleware.RequestID,
+			middleware.Trace,
+			middleware.Metrics,
+			middleware.AccessLog(initAccessLogger(), cfg.AccessLog.SkipPaths),
+			middleware.Recover(handlerImplement.RespondError),
+		)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Tracing error: %s", err.Error()))
+	}
+
 	server := http.Server{
 		Addr:    "localhost:8080",
 		Handler: middlewares(router),
 	}
+	server.RegisterOnShutdown(stopStreaming)
+
+	// scraped by Prometheus, which sends no credentials, so it is served on its own listener instead of the public one
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("GET /metrics", metrics.Handler())
+	metricsServer := http.Server{
+		Addr:    cfg.HTTP.MetricsAddr,
+		Handler: metricsMux,
+	}
+
+	background, stopBackground := context.WithCancel(context.Background())
+
+	if waitingRoom != nil {
+		go waitingRoom.Run(background)
+	}
 
 	go func() {
 		fmt.Println("Server is running in port ", server.Addr)
-		err := server.ListenAndServe()
 
-		if err != nil {
-			panic(err.Error())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Msg(fmt.Sprintf("Server error: %s", err.Error()))
+		}
+	}()
+
+	go func() {
+		log.Info().Msg(fmt.Sprintf("Metrics are served on %s", metricsServer.Addr))
+
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal().Msg(fmt.Sprintf("Metrics server error: %s", err.Error()))
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
-    signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-    <-quit
-    log.Info().Msg("Shutting down the server...")
-	errDbClose := database.Close()
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Info().Msg("Shutting down the server...")
 
-	if errDbClose != nil {
-		log.Fatal().Msg(fmt.Sprintf("Database shutdown error: %s", errDbClose.Error()))
+	if err := newLifecycle(&server, &metricsServer, stopBackground, shutdownTracing).Shutdown(cfg.HTTP.ShutdownTimeout); err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Server shutdown error: %s", err))
 	}
 
-    // Set a timeout for shutdown (for example, 5 seconds).
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-    defer cancel()
+	log.Info().Msg("Server gracefully stopped")
+}
 
-    if err := server.Shutdown(ctx); err != nil {
-        log.Fatal().Msg(fmt.Sprintf("Server shutdown error: %v", err))
-    }
-    log.Info().Msg("Server gracefully stopped")
-}
\ No newline at end of file
+// newLifecycle lays out the shutdown: traffic is drained first, then the requests and order jobs
+// in flight finish, and only then is the database they use closed
+func newLifecycle(server *http.Server, metricsServer *http.Server, stopBackground func(), shutdownTracing func(context.Context) error) *lifecycle.Lifecycle {
+	shutdown := lifecycle.New()
+
+	// keep serving while the load balancers see /readyz failing and move the traffic elsewhere
+	shutdown.OnShutdown("readiness", func(ctx context.Context) error {
+		healthChecker.Drain()
+		log.Info().Msg(fmt.Sprintf("Readiness is failing, draining traffic for %s", cfg.HTTP.DrainDelay))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.HTTP.DrainDelay):
+			return nil
+		}
+	})
+	// stops accepting connections and waits for the requests in flight, cutting the rest off at the deadline
+	shutdown.OnShutdown("http server", func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			return err
+		}
+
+		return nil
+	})
+	shutdown.OnShutdown("background jobs", func(ctx context.Context) error {
+		stopBackground()
+		return nil
+	})
+	// orders already accepted are still placed, while the database is open
+	shutdown.OnShutdown("ticket order workers", orderPool.Drain)
+	// scraped until the work above is done, for the shutdown to show up on the dashboards
+	shutdown.OnShutdown("metrics server", func(ctx context.Context) error {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			metricsServer.Close()
+			return err
+		}
+
+		return nil
+	})
+	shutdown.OnShutdown("tracing", shutdownTracing)
+	// left open when the workers didn't drain, jobs still placing orders need it to finish or give back what they took
+	shutdown.OnRelease("database", func(ctx context.Context) error {
+		return database.Close()
+	})
+
+	if accessLogFile != nil {
+		shutdown.OnRelease("access log", func(ctx context.Context) error {
+			return accessLogFile.Close()
+		})
+	}
+
+	return shutdown
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"ticket_goroutine/internal/provider/db"
+
+	"github.com/rs/zerolog/log"
+)
+
+func newMigrator() *db.Migrator {
+	migrator, err := db.NewMigrator(database, cfg.DB.Driver)
+
+	if err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Migration setup error: %s", err.Error()))
+	}
+
+	return migrator
+}
+
+func autoMigrate() {
+	log.Info().Msg("Auto migrate is enabled, applying pending migrations")
+
+	if err := newMigrator().Up(context.Background()); err != nil {
+		log.Fatal().Msg(fmt.Sprintf("Auto migrate error: %s", err.Error()))
+	}
+}
+
+// runMigrate handles "migrate up", "migrate down [steps]" and "migrate status"
+func runMigrate(args []string) {
+	ctx := context.Background()
+	migrator := newMigrator()
+
+	if len(args) == 0 {
+		fmt.Println("usage: migrate up | down [steps] | status")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+
+			if err != nil || n < 1 {
+				log.Fatal().Msg(fmt.Sprintf("Invalid number of steps: %s", args[1]))
+			}
+
+			steps = n
+		}
+
+		if err := migrator.Down(ctx, steps); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+	case "status":
+		listOfStatus, err := migrator.Status(ctx)
+
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+
+		for _, status := range listOfStatus {
+			appliedAt := "pending"
+
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		fmt.Println("usage: migrate up | down [steps] | status")
+		os.Exit(2)
+	}
+}
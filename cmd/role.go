@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"ticket_goroutine/internal/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+// runGrantRole handles "grant-role <email> <role>", which is how the first admin is made
+func runGrantRole(args []string) {
+	if len(args) != 2 || !slices.Contains(domain.Roles, domain.Role(args[1])) {
+		fmt.Println("usage: grant-role <email> admin | organizer | customer | gate_staff")
+		os.Exit(2)
+	}
+
+	ctx := domain.WithActor(context.Background(), "cli")
+	user, err := userRepo.FindByEmail(ctx, args[0])
+
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	user.Role = domain.Role(args[1])
+
+	if err := userRepo.Update(ctx, &user); err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+
+	fmt.Printf("%s is now %s\n", user.Email, user.Role)
+}
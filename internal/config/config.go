@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	HTTP        HTTPConfig
+	DB          DatabaseConfig
+	Auth        AuthConfig
+	WaitingRoom WaitingRoomConfig
+	Orders      OrdersConfig
+	AccessLog   AccessLogConfig
+	Tracing     TracingConfig
+}
+
+type HTTPConfig struct {
+	// RequestTimeout is how long a handler may take, for routes without their own timeout
+	RequestTimeout time.Duration
+	// ListTimeout is how long the routes listing every row may take
+	ListTimeout time.Duration
+	// MetricsAddr is the listener /metrics is served on, apart from the public one since Prometheus sends no credentials
+	MetricsAddr string
+	// ChangeTimeout is how long an update or delete may take, it carries on past the request's own timeout
+	// so it isn't cut off halfway
+	ChangeTimeout time.Duration
+	// DrainDelay is how long the server keeps serving after a SIGTERM with /readyz failing,
+	// for load balancers to notice and stop sending traffic before it shuts down
+	DrainDelay time.Duration
+	// HealthCheckTimeout bounds each dependency check of /readyz
+	HealthCheckTimeout time.Duration
+	// ShutdownTimeout bounds the whole shutdown, DrainDelay included, keep it under the orchestrator's grace period
+	ShutdownTimeout time.Duration
+}
+
+type DatabaseConfig struct {
+	// Driver is either "postgresql" or "sqlite"
+	Driver   string
+	Username string
+	Password string
+	Host     string
+	Port     string
+	// Name is the database name, or the file path (or ":memory:") for sqlite
+	Name string
+	// AutoMigrate applies pending migrations on startup, on by default for sqlite
+	AutoMigrate bool
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	PingAttempts    int
+	PingBackoff     time.Duration
+	PingTimeout     time.Duration
+}
+
+type AuthConfig struct {
+	// Secret signs the tokens, when empty a random one is generated and tokens stop working after a restart
+	Secret     string
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// WaitingRoomConfig turns on the queue in front of buying tickets, for on-sales bigger than the database can take at once
+type WaitingRoomConfig struct {
+	Enabled     bool
+	AdmitBatch  int
+	AdmitEvery  time.Duration
+	AdmitTTL    time.Duration
+	IdleTimeout time.Duration
+}
+
+// OrdersConfig sizes the worker pool placing ticket orders, orders beyond QueueSize waiting are refused with 503
+type OrdersConfig struct {
+	Workers    int
+	QueueSize  int
+	JobTimeout time.Duration
+	// QueueWait is how long an order may wait for a worker, it is failed instead of placed once it waited longer
+	QueueWait time.Duration
+	// ResultTTL is how long the outcome of an order request can still be polled
+	ResultTTL time.Duration
+}
+
+// AccessLogConfig is where the one line per request goes, and which requests are too frequent to be worth it
+type AccessLogConfig struct {
+	Enabled bool
+	// Format is either "json" or "console"
+	Format string
+	// File is written to instead of stdout when set, moved aside once it reaches MaxSizeMB (0 never)
+	File       string
+	MaxSizeMB  int
+	MaxBackups int
+	// SkipPaths are left out, such as health checks polled by load balancers
+	SkipPaths []string
+}
+
+// TracingConfig picks where spans are sent. The OTLP endpoint is read by the exporter from OTEL_EXPORTER_OTLP_ENDPOINT.
+type TracingConfig struct {
+	// Exporter is "none", "stdout" or "otlp"
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+func Load() Config {
+	driver := getEnv("DB_DRIVER", "postgresql")
+
+	return Config{
+		HTTP: HTTPConfig{
+			RequestTimeout:     getEnvDuration("HTTP_REQUEST_TIMEOUT", 2*time.Second),
+			ListTimeout:        getEnvDuration("HTTP_LIST_TIMEOUT", 30*time.Second),
+			MetricsAddr:        getEnv("METRICS_ADDR", "localhost:9090"),
+			ChangeTimeout:      getEnvDuration("HTTP_CHANGE_TIMEOUT", 10*time.Second),
+			DrainDelay:         getEnvDuration("HTTP_DRAIN_DELAY", 5*time.Second),
+			HealthCheckTimeout: getEnvDuration("HTTP_HEALTH_CHECK_TIMEOUT", time.Second),
+			ShutdownTimeout:    getEnvDuration("HTTP_SHUTDOWN_TIMEOUT", 25*time.Second),
+		},
+		DB: DatabaseConfig{
+			Driver:      driver,
+			Username:    getEnv("DB_USERNAME", "postgres"),
+			Password:    getEnv("DB_PASSWORD", "root"),
+			Host:        getEnv("DB_HOST", "localhost"),
+			Port:        getEnv("DB_PORT", "5432"),
+			Name:        getEnv("DB_NAME", "phincon_golang_ticket"),
+			AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", driver == "sqlite"),
+
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+			PingAttempts:    getEnvInt("DB_PING_ATTEMPTS", 5),
+			PingBackoff:     getEnvDuration("DB_PING_BACKOFF", 500*time.Millisecond),
+			PingTimeout:     getEnvDuration("DB_PING_TIMEOUT", 2*time.Second),
+		},
+		Auth: AuthConfig{
+			Secret:     getEnv("JWT_SECRET", ""),
+			Issuer:     getEnv("JWT_ISSUER", "ticket_goroutine"),
+			AccessTTL:  getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+			RefreshTTL: getEnvDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
+		},
+		WaitingRoom: WaitingRoomConfig{
+			Enabled:     getEnvBool("WAITING_ROOM_ENABLED", false),
+			AdmitBatch:  getEnvInt("WAITING_ROOM_ADMIT_BATCH", 50),
+			AdmitEvery:  getEnvDuration("WAITING_ROOM_ADMIT_EVERY", time.Second),
+			AdmitTTL:    getEnvDuration("WAITING_ROOM_ADMIT_TTL", 5*time.Minute),
+			IdleTimeout: getEnvDuration("WAITING_ROOM_IDLE_TIMEOUT", 30*time.Second),
+		},
+		Orders: OrdersConfig{
+			Workers:    getEnvInt("ORDER_WORKERS", 8),
+			QueueSize:  getEnvInt("ORDER_QUEUE_SIZE", 256),
+			JobTimeout: getEnvDuration("ORDER_JOB_TIMEOUT", 7*time.Second),
+			QueueWait:  getEnvDuration("ORDER_QUEUE_WAIT", 3*time.Second),
+			ResultTTL:  getEnvDuration("ORDER_RESULT_TTL", 10*time.Minute),
+		},
+		AccessLog: AccessLogConfig{
+			Enabled:    getEnvBool("ACCESS_LOG_ENABLED", true),
+			Format:     getEnv("ACCESS_LOG_FORMAT", "json"),
+			File:       getEnv("ACCESS_LOG_FILE", ""),
+			MaxSizeMB:  getEnvInt("ACCESS_LOG_MAX_SIZE_MB", 100),
+			MaxBackups: getEnvInt("ACCESS_LOG_MAX_BACKUPS", 5),
+			SkipPaths:  getEnvList("ACCESS_LOG_SKIP_PATHS", []string{"/healthz", "/readyz"}),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			ServiceName: getEnv("TRACING_SERVICE_NAME", "ticket_goroutine"),
+			SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+		},
+	}
+}
+
+func getEnv(key string, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// getEnvList reads comma separated values, such as "/healthz,/readyz"
+func getEnvList(key string, fallback []string) []string {
+	var values []string
+
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return fallback
+	}
+
+	return values
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+// getEnvDuration reads values such as "500ms", "30s" or "5m"
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+
+	if err != nil || value <= 0 {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Scopes an API key can be granted, partners only ever act on ticket orders
+const (
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+)
+
+// Scopes lists every scope, the validate tag on APIKey.Scopes must be kept in sync
+var Scopes = []string{ScopeOrdersRead, ScopeOrdersWrite}
+
+// APIKey lets a partner backend act as UserID without logging in.
+// Only Prefix and a hash are stored, the full Key is returned once when the key is created.
+type APIKey struct {
+	KeyID      int        `json:"KeyID"`
+	UserID     int        `json:"UserID" validate:"required,gt=0"`
+	Name       string     `json:"Name" validate:"required"`
+	Scopes     []string   `json:"Scopes" validate:"required,min=1,dive,oneof=orders:read orders:write"`
+	ExpiresAt  *time.Time `json:"ExpiresAt,omitempty"`
+	Prefix     string     `json:"Prefix"`
+	Key        string     `json:"Key,omitempty"`
+	KeyHash    string     `json:"-"`
+	LastUsedAt *time.Time `json:"LastUsedAt,omitempty"`
+	Audit
+}
+
+// APIKeyUsage is one request made with an API key
+type APIKeyUsage struct {
+	UsageID    int       `json:"UsageID"`
+	KeyID      int       `json:"KeyID"`
+	Method     string    `json:"Method"`
+	Path       string    `json:"Path"`
+	RemoteAddr string    `json:"RemoteAddr"`
+	UsedAt     time.Time `json:"UsedAt"`
+}
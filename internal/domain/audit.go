@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Audit is embedded in every entity and filled in by the repositories
+type Audit struct {
+	CreatedAt time.Time  `json:"CreatedAt"`
+	UpdatedAt time.Time  `json:"UpdatedAt"`
+	DeletedAt *time.Time `json:"DeletedAt,omitempty"`
+	CreatedBy string     `json:"CreatedBy"`
+	UpdatedBy string     `json:"UpdatedBy"`
+}
+
+type contextKey string
+
+const (
+	actorKey          contextKey = "actor"
+	includeDeletedKey contextKey = "include_deleted"
+)
+
+// SystemActor is recorded as created_by and updated_by when no caller is known
+const SystemActor = "system"
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+
+	return SystemActor
+}
+
+// WithIncludeDeleted makes repository reads return soft deleted rows as well
+func WithIncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey, true)
+}
+
+func IncludeDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey).(bool)
+	return include
+}
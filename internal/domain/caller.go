@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+const callerKey contextKey = "caller"
+
+// Caller is the authenticated user behind a request. Requests made with an API key
+// act as the key's user but carry the key's scopes instead of a role.
+type Caller struct {
+	UserID   int
+	Role     Role
+	APIKeyID int
+	Scopes   []string
+}
+
+// Is reports whether the caller has one of roles
+func (c Caller) Is(roles ...Role) bool {
+	return c.APIKeyID == 0 && slices.Contains(roles, c.Role)
+}
+
+// Has reports whether the caller's API key was granted scope
+func (c Caller) Has(scope string) bool {
+	return c.APIKeyID != 0 && slices.Contains(c.Scopes, scope)
+}
+
+// WithCaller records the authenticated user behind the request, who also becomes the audit actor
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	ctx = context.WithValue(ctx, callerKey, caller)
+
+	if caller.APIKeyID != 0 {
+		return WithActor(ctx, fmt.Sprintf("api_key:%d", caller.APIKeyID))
+	}
+
+	return WithActor(ctx, fmt.Sprintf("user:%d", caller.UserID))
+}
+
+// CallerFromContext returns the authenticated user, ok is false for requests that were not authenticated
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey).(Caller)
+	return caller, ok
+}
@@ -0,0 +1,10 @@
+package domain
+
+type Credentials struct {
+	Email    string `json:"Email" validate:"required,email"`
+	Password string `json:"Password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"RefreshToken" validate:"required"`
+}
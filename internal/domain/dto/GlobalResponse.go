@@ -7,6 +7,6 @@ type GlobalResponse struct {
 	StatusDesc     string
 	Message        string
 	RequestCreated string
-	ProcessTime time.Duration
+	ProcessTime    time.Duration // measured from when the handler received the request
 	Data           interface{}
 }
\ No newline at end of file
@@ -0,0 +1,12 @@
+package dto
+
+type HealthResponse struct {
+	Status string
+	Checks map[string]HealthCheckResponse `json:",omitempty"`
+}
+
+type HealthCheckResponse struct {
+	Status   string
+	Error    string `json:",omitempty"`
+	Duration string
+}
@@ -0,0 +1,18 @@
+package dto
+
+// Statuses of an order request, an order placed through the order queue
+const (
+	OrderRequestQueued     = "queued"
+	OrderRequestProcessing = "processing"
+	OrderRequestCompleted  = "completed"
+	OrderRequestFailed     = "failed"
+)
+
+type OrderRequestResponse struct {
+	RequestID string
+	Status    string
+	Order     *TicketOrderResponseSave `json:",omitempty"` // set once Status is completed
+	Error     string                   `json:",omitempty"` // set once Status is failed
+	// Failure is why the order failed, for the handler to pick a status or message from
+	Failure error `json:"-"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// ProblemDetails is an RFC 7807 error document, sent instead of GlobalResponse when the client accepts application/problem+json
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type QueuePositionResponse struct {
+	QueueToken           string
+	Position             int // 0 once admitted
+	Admitted             bool
+	AdmittedUntil        *time.Time `json:",omitempty"` // the QueueToken must be sent as X-Queue-Token with orders until then
+	EstimatedWaitSeconds int
+}
@@ -0,0 +1,9 @@
+package dto
+
+type TokenResponse struct {
+	AccessToken      string
+	RefreshToken     string
+	TokenType        string
+	ExpiresIn        int // seconds until AccessToken expires
+	RefreshExpiresIn int // seconds until RefreshToken expires
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds every layer wraps its failures in, so the handlers can pick a status without reading messages
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrInsufficientStock   = errors.New("not enough stock")
+	ErrInsufficientBalance = errors.New("not enough balance")
+	ErrValidation          = errors.New("validation failed")
+	ErrTimeout             = errors.New("request timed out")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrUnavailable         = errors.New("service unavailable")
+)
+
+// kindError is a sentinel with its own message that still matches the kind it belongs to
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+var ErrVersionConflict error = &kindError{message: "version conflict", kind: ErrConflict}
+
+// ErrHasPaidOrders is returned when deleting something that paid ticket orders still point to
+var ErrHasPaidOrders error = &kindError{message: "has paid orders", kind: ErrConflict}
+
+// ErrAlreadyExists is returned when saving would duplicate a row that must be unique
+var ErrAlreadyExists error = &kindError{message: "already exist", kind: ErrConflict}
+
+// ErrNotAdmitted is returned when buying without having been let through the waiting room
+var ErrNotAdmitted error = &kindError{message: "not admitted from the waiting room yet", kind: ErrTooManyRequests}
+
+// NotFoundError is returned when no row exists for the requested ID
+type NotFoundError struct {
+	Entity string
+	ID     int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no %s found with ID %d", e.Entity, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ConflictError is returned when an update lost a race because the row's version changed since it was read
+type ConflictError struct {
+	Entity  string
+	ID      int
+	Version int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s with ID %d was modified concurrently, expected version %d", e.Entity, e.ID, e.Version)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict || target == ErrConflict
+}
+
+// ValidationError is returned for input that cannot be accepted, with a message per field when it failed struct validation.
+// Cause keeps the validator's own errors so the messages can be translated for the client.
+type ValidationError struct {
+	Message string
+	Fields  map[string]string
+	Cause   error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Message == "" {
+		return ErrValidation.Error()
+	}
+
+	return e.Message
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Cause
+}
+
+// Timeout marks err, normally ctx.Err(), as the request running out of time
+func Timeout(err error) error {
+	return fmt.Errorf("%w: %w", ErrTimeout, err)
+}
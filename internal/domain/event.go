@@ -3,4 +3,8 @@ package domain
 type Event struct {
 	EventID   int    `json:"EventID"`
 	EventName string `json:"EventName" validate:"required"`
-}
\ No newline at end of file
+	// OrganizerID is the organizer user owning the event, 0 for events only admins manage
+	OrganizerID int `json:"OrganizerID" validate:"omitempty,gt=0"`
+	Version     int `json:"Version"`
+	Audit
+}
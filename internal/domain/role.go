@@ -0,0 +1,13 @@
+package domain
+
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"
+	RoleOrganizer Role = "organizer"
+	RoleCustomer  Role = "customer"
+	RoleGateStaff Role = "gate_staff"
+)
+
+// Roles lists every role, the validate tag on User.Role must be kept in sync
+var Roles = []Role{RoleAdmin, RoleOrganizer, RoleCustomer, RoleGateStaff}
@@ -1,10 +1,15 @@
 package domain
 
+// TicketTypes are the values accepted for Ticket.Type by the ticket_type validation rule
+var TicketTypes = []string{"VIP", "CAT 1", "CAT 2", "CAT 3", "REGULAR"}
+
 type Ticket struct {
 	TicketID int     `json:"TicketID"`
 	EventID  int     `json:"EventID" validate:"required,number,gt=0"`
 	Name     string  `json:"Name" validate:"required"`
 	Price    float64 `json:"Price" validate:"required,number,gt=0"`
-	Stock    int     `json:"Stock" validate:"required,number,gt=0"`
-	Type     string  `json:"Type" validate:"required"`
-}
\ No newline at end of file
+	Stock    int     `json:"Stock" validate:"number,min=0"`
+	Type     string  `json:"Type" validate:"required,ticket_type"`
+	Version  int     `json:"Version"`
+	Audit
+}
@@ -1,9 +1,11 @@
 package domain
 
 type TicketOrder struct {
-	OrderID    int `json:"OrderID"`
-	TicketID   int `json:"TicketID" validate:"required,number,gt=0"`
-	UserID     int `json:"UserID" validate:"required,number,gt=0"`
+	OrderID  int `json:"OrderID"`
+	TicketID int `json:"TicketID" validate:"required,number,gt=0"`
+	// UserID is set from the authenticated caller, a value sent in the body is ignored
+	UserID     int `json:"UserID" validate:"omitempty,number,gt=0"`
 	Amount     int `json:"Amount" validate:"required,number,gt=0"`
 	TotalPrice float64
-}
\ No newline at end of file
+	Audit
+}
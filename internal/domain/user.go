@@ -4,6 +4,13 @@ type User struct {
 	UserID      int     `json:"UserID"`
 	Email       string  `json:"Email" validate:"required,email"`
 	Name        string  `json:"Name" validate:"required"`
-	PhoneNumber string  `json:"PhoneNumber" validate:"required,e164"`
-	Balance     float64 `json:"Balance" validate:"required,number,gt=0"`
-}
\ No newline at end of file
+	PhoneNumber string  `json:"PhoneNumber" validate:"required,e164,phone_region=62"`
+	Balance     float64 `json:"Balance" validate:"number,min=0"`
+	// Password is only ever read from requests, the repositories store PasswordHash
+	Password     string `json:"Password,omitempty" validate:"omitempty,min=8"`
+	PasswordHash string `json:"-"`
+	// Role defaults to customer, only an admin may give or change it
+	Role    Role `json:"Role" validate:"omitempty,oneof=admin organizer customer gate_staff"`
+	Version int  `json:"Version"`
+	Audit
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type APIKeyHandlerInterface interface {
+	APIKeySave
+	APIKeyFindById
+	APIKeyGetAll
+	APIKeyDelete
+	APIKeyGetUsage
+}
+
+type APIKeySave interface {
+	Save(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type APIKeyFindById interface {
+	FindById(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type APIKeyGetAll interface {
+	GetAll(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type APIKeyDelete interface {
+	Delete(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type APIKeyGetUsage interface {
+	GetUsage(responseWritter http.ResponseWriter, request *http.Request)
+}
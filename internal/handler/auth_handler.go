@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type AuthHandlerInterface interface {
+	AuthLogin
+	AuthRefresh
+}
+
+type AuthLogin interface {
+	Login(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type AuthRefresh interface {
+	Refresh(responseWritter http.ResponseWriter, request *http.Request)
+}
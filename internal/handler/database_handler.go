@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type DatabaseHandlerInterface interface {
+	DatabaseStats
+}
+
+type DatabaseStats interface {
+	Stats(responseWritter http.ResponseWriter, request *http.Request)
+}
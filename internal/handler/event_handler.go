@@ -8,6 +8,10 @@ type EventHandlerInterface interface {
 	EventSave
 	EventFindById
 	EventGetAll
+	EventGetByOrganizer
+	EventUpdate
+	EventPatch
+	EventDelete
 }
 
 type EventSave interface {
@@ -20,4 +24,20 @@ type EventFindById interface {
 
 type EventGetAll interface {
 	GetAll(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type EventGetByOrganizer interface {
+	GetByOrganizer(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type EventUpdate interface {
+	Update(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type EventPatch interface {
+	Patch(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type EventDelete interface {
+	Delete(responseWritter http.ResponseWriter, request *http.Request)
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type HealthHandlerInterface interface {
+	HealthLive
+	HealthReady
+}
+
+type HealthLive interface {
+	Live(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type HealthReady interface {
+	Ready(responseWritter http.ResponseWriter, request *http.Request)
+}
@@ -0,0 +1,132 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/handler"
+	"ticket_goroutine/internal/usecase"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type APIKeyHandler struct {
+	usecase       usecase.APIKeyUseCaseInterface
+	changeTimeout time.Duration
+}
+
+func NewAPIKeyHandler(usecase usecase.APIKeyUseCaseInterface, changeTimeout time.Duration) (handler.APIKeyHandlerInterface) {
+	return APIKeyHandler {
+		usecase: usecase,
+		changeTimeout: changeTimeout,
+	}
+}
+
+func (h APIKeyHandler) Save(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering api key handler save")
+	respond := newResponder(responseWriter, request)
+
+	ctx := request.Context()
+
+	key, ok := decodeJSON[domain.APIKey](respond, request, "Failed to save API key because didn't pass the validation")
+
+	if !ok {
+		return
+	}
+
+	logger.Debug().
+		Int("User ID: ", key.UserID).
+		Str("Name: ", key.Name).
+		Msg("Continuing api key save process")
+
+	savedKey, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.Save(ctx, key)
+	})
+
+	if !ok {
+		return
+	}
+
+	// the plain key is in this response only, it cannot be read again
+	logger.Info().Msg("API key created successfully and returning json")
+	respond.JSON(http.StatusCreated, "Created", savedKey)
+}
+
+func (h APIKeyHandler) FindById(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering api key handler find by id")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
+
+	id, ok := pathID(respond, request)
+
+	if !ok {
+		return
+	}
+
+	foundKey, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.FindById(ctx, id)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("API key fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", foundKey)
+}
+
+func (h APIKeyHandler) GetAll(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering api key get all handler")
+	respond := newResponder(responseWriter, request)
+	ctx := readContext(request)
+
+	allKeys, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.GetAll(ctx)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("API keys fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", allKeys)
+}
+
+func (h APIKeyHandler) Delete(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering api key handler delete")
+	respond := newResponder(responseWriter, request)
+
+	modifyById(respond, request, h.changeTimeout, "Deleted", func(ctx context.Context, id int) (interface{}, error) {
+		return nil, h.usecase.Delete(ctx, id)
+	})
+}
+
+func (h APIKeyHandler) GetUsage(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering api key handler get usage")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
+
+	id, ok := pathID(respond, request)
+
+	if !ok {
+		return
+	}
+
+	listOfUsage, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.GetUsage(ctx, id)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("API key usage fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", listOfUsage)
+}
@@ -0,0 +1,73 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/handler"
+	"ticket_goroutine/internal/usecase"
+
+	"github.com/rs/zerolog"
+)
+
+type AuthHandler struct {
+	usecase usecase.AuthUseCaseInterface
+}
+
+func NewAuthHandler(usecase usecase.AuthUseCaseInterface) (handler.AuthHandlerInterface) {
+	return AuthHandler {
+		usecase: usecase,
+	}
+}
+
+func (h AuthHandler) Login(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering auth handler login")
+	respond := newResponder(responseWriter, request)
+
+	ctx := request.Context()
+
+	credentials, ok := decodeJSON[domain.Credentials](respond, request, "Failed to log in because didn't pass the validation")
+
+	if !ok {
+		return
+	}
+
+	logger.Debug().Str("Email: ", credentials.Email).Msg("Continuing login process")
+
+	tokens, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.Login(ctx, credentials)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("Logged in successfully and returning json")
+	respond.JSON(http.StatusOK, "OK", tokens)
+}
+
+func (h AuthHandler) Refresh(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering auth handler refresh")
+	respond := newResponder(responseWriter, request)
+
+	ctx := request.Context()
+
+	refresh, ok := decodeJSON[domain.RefreshRequest](respond, request, "Failed to refresh tokens because didn't pass the validation")
+
+	if !ok {
+		return
+	}
+
+	tokens, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.Refresh(ctx, refresh.RefreshToken)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("Tokens refreshed successfully and returning json")
+	respond.JSON(http.StatusOK, "OK", tokens)
+}
@@ -0,0 +1,41 @@
+package impl
+
+import (
+	"database/sql"
+	"net/http"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/handler"
+
+	"github.com/rs/zerolog"
+)
+
+type DatabaseHandler struct {
+	db *sql.DB
+}
+
+func NewDatabaseHandler(database *sql.DB) (handler.DatabaseHandlerInterface) {
+	return DatabaseHandler {
+		db: database,
+	}
+}
+
+func (h DatabaseHandler) Stats(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering database handler stats")
+	respond := newResponder(responseWriter, request)
+
+	stats := h.db.Stats()
+
+	logger.Info().Msg("Database pool stats fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", dto.DatabaseStatsResponse {
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections: stats.OpenConnections,
+		InUse: stats.InUse,
+		Idle: stats.Idle,
+		WaitCount: stats.WaitCount,
+		WaitDuration: stats.WaitDuration.String(),
+		MaxIdleClosed: stats.MaxIdleClosed,
+		MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	})
+}
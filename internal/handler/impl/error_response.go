@@ -0,0 +1,107 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// retryUnavailableAfter is the Retry-After, in seconds, sent with 503 responses
+const retryUnavailableAfter = "2"
+
+// statusFor maps the domain error kinds to the status code every handler answers with
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, domain.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrInsufficientBalance):
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error answers a request that failed in a use case or repository
+func (r *responder) Error(err error) {
+	zerolog.Ctx(r.request.Context()).Error().Err(err).Msg("request failed")
+	statusCode := statusFor(err)
+
+	switch statusCode {
+	case http.StatusGatewayTimeout:
+		r.TimedOut()
+	case http.StatusUnauthorized:
+		r.writer.Header().Set("WWW-Authenticate", `Bearer realm="ticket_goroutine"`)
+		r.fail(statusCode, err.Error(), nil)
+	case http.StatusServiceUnavailable:
+		r.writer.Header().Set("Retry-After", retryUnavailableAfter)
+		r.fail(statusCode, err.Error(), nil)
+	case http.StatusInternalServerError:
+		// the cause is logged above, it is not leaked to the client
+		r.fail(statusCode, http.StatusText(statusCode), nil)
+	default:
+		var validationError *domain.ValidationError
+
+		if errors.As(err, &validationError) {
+			r.fail(statusCode, err.Error(), r.fields(validationError))
+			return
+		}
+
+		r.fail(statusCode, err.Error(), nil)
+	}
+}
+
+// RespondError answers a request that failed outside a handler, such as in a middleware, the same way handlers do
+func RespondError(responseWriter http.ResponseWriter, request *http.Request, err error) {
+	newResponder(responseWriter, request).Error(err)
+}
+
+// ValidationError sends the per field messages of a failed validation under message, or maps any other error
+func (r *responder) ValidationError(errValidate error, message string) {
+	logger := zerolog.Ctx(r.request.Context())
+	var validationError *domain.ValidationError
+
+	if !errors.As(errValidate, &validationError) {
+		logger.Trace().Msg("Error with validator")
+		r.Error(errValidate)
+		return
+	}
+
+	logger.Trace().Msg("User input error")
+	for field, fieldMessage := range validationError.Fields {
+		logger.Error().Msg(field + ": " + fieldMessage)
+	}
+
+	r.fail(http.StatusBadRequest, message, r.fields(validationError))
+}
+
+// fields translates the per field messages into the client's Accept-Language when the validator produced them
+func (r *responder) fields(validationError *domain.ValidationError) map[string]string {
+	var fieldErrors validator.ValidationErrors
+
+	if !errors.As(validationError.Cause, &fieldErrors) {
+		return validationError.Fields
+	}
+
+	return utils.TranslateValidation(fieldErrors, r.request.Header.Get("Accept-Language"))
+}
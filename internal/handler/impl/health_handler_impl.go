@@ -0,0 +1,54 @@
+package impl
+
+import (
+	"fmt"
+	"net/http"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/handler"
+	"ticket_goroutine/internal/provider/health"
+
+	"github.com/rs/zerolog"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) (handler.HealthHandlerInterface) {
+	return HealthHandler {
+		checker: checker,
+	}
+}
+
+// Live answers as long as the process can serve requests at all, its dependencies are left to Ready
+func (h HealthHandler) Live(responseWriter http.ResponseWriter, request *http.Request) {
+	respond := newResponder(responseWriter, request)
+	respond.JSON(http.StatusOK, "OK", dto.HealthResponse{Status: health.StatusUp})
+}
+
+// Ready answers 503 when a dependency is down or the server is shutting down, so no traffic is sent to it
+func (h HealthHandler) Ready(responseWriter http.ResponseWriter, request *http.Request) {
+	respond := newResponder(responseWriter, request)
+	report := h.checker.Ready(request.Context())
+
+	response := dto.HealthResponse{
+		Status: report.Status,
+		Checks: make(map[string]dto.HealthCheckResponse, len(report.Checks)),
+	}
+
+	for name, result := range report.Checks {
+		response.Checks[name] = dto.HealthCheckResponse{
+			Status:   result.Status,
+			Error:    result.Error,
+			Duration: result.Duration.String(),
+		}
+	}
+
+	if report.Status != health.StatusUp {
+		zerolog.Ctx(request.Context()).Warn().Msg(fmt.Sprintf("Readiness check failed with status %s", report.Status))
+		respond.JSON(http.StatusServiceUnavailable, "Not Ready", response)
+		return
+	}
+
+	respond.JSON(http.StatusOK, "OK", response)
+}
@@ -0,0 +1,77 @@
+package impl
+
+import (
+	"context"
+	"net/http"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// await runs call on its own goroutine and hands its result back over a channel, so only the request
+// goroutine ever writes the response. It answers the request itself, reporting false, when call failed
+// or the request's deadline passed first; call then finishes on its own and its result is dropped.
+func await(respond *responder, ctx context.Context, call func(ctx context.Context) (interface{}, error)) (interface{}, bool) {
+	logger := zerolog.Ctx(ctx)
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	// buffered so a call finishing after the timeout never blocks
+	results := make(chan result, 1)
+	logger.Info().Msg("Channel created")
+
+	utils.Go(func() {
+		logger.Trace().Msg("Inside goroutine calling the use case")
+		value, err := call(ctx)
+		results <- result{value: value, err: err}
+	}, func(err *utils.PanicError) {
+		err.Log(respond.request)
+		results <- result{err: err}
+	})
+
+	select {
+	case <- ctx.Done():
+		logger.Trace().Msg("Request timeout channel")
+		respond.Error(domain.Timeout(ctx.Err()))
+		return nil, false
+	case res := <- results:
+		logger.Trace().Msg("Request completed")
+
+		if res.err != nil {
+			logger.Trace().Msg("Checking error cause")
+			respond.Error(res.err)
+			return nil, false
+		}
+
+		return res.value, true
+	}
+}
+
+// modifyById runs an update or delete use case for the {id} path value and writes its outcome.
+// The change doesn't stop when the request times out, it may take several writes that must not be
+// left halfway, so it runs until timeout instead and the client is only told it timed out.
+func modifyById(respond *responder, request *http.Request, timeout time.Duration, successMessage string, run func(ctx context.Context, id int) (interface{}, error)) {
+	id, ok := pathID(respond, request)
+
+	if !ok {
+		return
+	}
+
+	result, ok := await(respond, request.Context(), func(ctx context.Context) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		return run(ctx, id)
+	})
+
+	if !ok {
+		return
+	}
+
+	zerolog.Ctx(request.Context()).Info().Msg("Data modified successfully and returning json")
+	respond.JSON(http.StatusOK, successMessage, result)
+}
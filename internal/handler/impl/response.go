@@ -0,0 +1,221 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxBodyBytes caps every request body a handler decodes
+const maxBodyBytes = 1 << 20
+
+// problemContentType is the RFC 7807 media type clients ask for in Accept to get errors as dto.ProblemDetails
+const problemContentType = "application/problem+json"
+
+// responder writes the dto.GlobalResponse of one request, timed from when the handler received it.
+// Only the first write goes out, so a handler answering twice by mistake cannot corrupt the response.
+type responder struct {
+	writer  http.ResponseWriter
+	request *http.Request
+	started time.Time
+	mtx     sync.Mutex
+	written bool
+}
+
+func newResponder(responseWriter http.ResponseWriter, request *http.Request) *responder {
+	return &responder{
+		writer:  responseWriter,
+		request: request,
+		started: time.Now(),
+	}
+}
+
+// claim reserves the single write of this response, reporting false when it was already made
+func (r *responder) claim(statusCode int, message string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.written {
+		zerolog.Ctx(r.request.Context()).Debug().Msg(fmt.Sprintf("Response already written, dropping %d %s", statusCode, message))
+		return false
+	}
+
+	r.written = true
+	return true
+}
+
+// JSON sends data with the given status and message
+func (r *responder) JSON(statusCode int, message string, data interface{}) {
+	if !r.claim(statusCode, message) {
+		return
+	}
+
+	response := dto.GlobalResponse {
+		StatusCode: statusCode,
+		StatusDesc: http.StatusText(statusCode),
+		Message: message,
+		RequestCreated: r.started.Format("2006-01-02 15:04:05"),
+		ProcessTime: time.Since(r.started),
+		Data: data,
+	}
+
+	r.writer.Header().Set("Content-Type", "application/json")
+	r.writer.Header().Set("X-Content-Type-Options", "nosniff")
+	r.writer.WriteHeader(statusCode)
+	json.NewEncoder(r.writer).Encode(response)
+}
+
+// fail sends an error, as dto.ProblemDetails when the client accepts it and as dto.GlobalResponse otherwise
+func (r *responder) fail(statusCode int, message string, fields map[string]string) {
+	if !strings.Contains(r.request.Header.Get("Accept"), problemContentType) {
+		if fields != nil {
+			r.JSON(statusCode, message, fields)
+			return
+		}
+
+		r.JSON(statusCode, message, "")
+		return
+	}
+
+	if !r.claim(statusCode, message) {
+		return
+	}
+
+	problem := dto.ProblemDetails {
+		Type: "about:blank",
+		Title: http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: message,
+		Instance: r.request.URL.Path,
+		Errors: fields,
+	}
+
+	r.writer.Header().Set("Content-Type", problemContentType)
+	r.writer.Header().Set("X-Content-Type-Options", "nosniff")
+	r.writer.WriteHeader(statusCode)
+	json.NewEncoder(r.writer).Encode(problem)
+}
+
+// TimedOut answers a request whose route timeout ran out before the use case finished.
+// It is a 504 since the client sent its request in time, the server was the one too slow.
+func (r *responder) TimedOut() {
+	r.fail(http.StatusGatewayTimeout, "Request Timed Out", nil)
+}
+
+// decodeJSON reads and validates a T from the body, answering the request itself when it is invalid.
+// Unknown fields and bodies over maxBodyBytes are rejected.
+func decodeJSON[T any](respond *responder, request *http.Request, validationMessage string) (T, bool) {
+	logger := zerolog.Ctx(request.Context())
+	var entity T
+
+	logger.Trace().Msg("Decoding json")
+	decoder := json.NewDecoder(http.MaxBytesReader(respond.writer, request.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&entity); err != nil {
+		logger.Trace().Msg("JSON decode error")
+		respond.Error(decodeError(err))
+		return entity, false
+	}
+
+	logger.Trace().Msg("Validating user input")
+	errValidate := utils.ValidateStruct(&entity)
+
+	if errValidate != nil {
+		logger.Trace().Msg("Validation error")
+		respond.ValidationError(errValidate, validationMessage)
+		return entity, false
+	}
+
+	return entity, true
+}
+
+// readPatch reads a JSON merge patch document from the body
+func readPatch(respond *responder, request *http.Request) ([]byte, bool) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Reading merge patch")
+	patch, err := io.ReadAll(http.MaxBytesReader(respond.writer, request.Body, maxBodyBytes))
+
+	if err == nil && !json.Valid(patch) {
+		err = fmt.Errorf("request body is not a valid JSON merge patch")
+	}
+
+	if err != nil {
+		respond.Error(decodeError(err))
+		return nil, false
+	}
+
+	return patch, true
+}
+
+func decodeError(err error) error {
+	var maxBytesError *http.MaxBytesError
+
+	if errors.As(err, &maxBytesError) {
+		return &domain.ValidationError{Message: fmt.Sprintf("request body is larger than %d bytes", maxBytesError.Limit)}
+	}
+
+	return &domain.ValidationError{Message: err.Error()}
+}
+
+// pathID reads the {id} path value, answering the request itself when it is not a number
+func pathID(respond *responder, request *http.Request) (int, bool) {
+	logger := zerolog.Ctx(request.Context())
+	idString := request.PathValue("id")
+	logger.Debug().Str("id", idString).Msg("Received id")
+
+	logger.Trace().Msg("Trying to convert id in string to int")
+	id, errConv := strconv.Atoi(idString)
+
+	if errConv != nil {
+		logger.Trace().Msg("Error happens when converting id string to int")
+		respond.Error(&domain.ValidationError{Message: errConv.Error()})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// readContext carries the include_deleted=true query flag down to the repositories. Only admins may see
+// soft deleted rows, the flag is ignored for everyone else.
+func readContext(request *http.Request) context.Context {
+	logger := zerolog.Ctx(request.Context())
+
+	if request.URL.Query().Get("include_deleted") != "true" {
+		return request.Context()
+	}
+
+	caller, ok := domain.CallerFromContext(request.Context())
+
+	if !ok || !caller.Is(domain.RoleAdmin) {
+		logger.Debug().Msg("Ignoring include_deleted, the caller is not an admin")
+		return request.Context()
+	}
+
+	logger.Debug().Msg("Soft deleted rows are included in this read")
+	return domain.WithIncludeDeleted(request.Context())
+}
+
+// callerID reads the user the auth middleware authenticated, answering the request itself when there is none
+func callerID(respond *responder, request *http.Request) (int, bool) {
+	caller, ok := domain.CallerFromContext(request.Context())
+
+	if !ok {
+		zerolog.Ctx(request.Context()).Error().Msg(fmt.Sprintf("No authenticated user on %s %s", request.Method, request.URL.Path))
+		respond.Error(fmt.Errorf("request is not authenticated: %w", domain.ErrUnauthorized))
+		return 0, false
+	}
+
+	return caller.UserID, true
+}
@@ -2,99 +2,41 @@ package impl
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 	"ticket_goroutine/internal/domain"
-	"ticket_goroutine/internal/domain/dto"
 	"ticket_goroutine/internal/handler"
 	"ticket_goroutine/internal/usecase"
-	"ticket_goroutine/utils"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type TicketHandler struct {
 	usecaseTicket usecase.TicketUseCaseInterface
+	changeTimeout time.Duration
 }
 
-func NewTicketHandler(usecaseTicket usecase.TicketUseCaseInterface) (handler.TicketHandlerInterface) {
+func NewTicketHandler(usecaseTicket usecase.TicketUseCaseInterface, changeTimeout time.Duration) (handler.TicketHandlerInterface) {
 	return TicketHandler {
 		usecaseTicket: usecaseTicket,
+		changeTimeout: changeTimeout,
 	}
 }
 
 func (h TicketHandler) Save(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket handler save")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	var ticket domain.Ticket
-
-	log.Trace().Msg("Decoding json")
-	err := json.NewDecoder(request.Body).Decode(&ticket)
-
-	if err != nil {
-		log.Trace().Msg("JSON decode error")
-		log.Error().Str("Error message: ", err.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
-		return
-	}
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket handler save")
+	respond := newResponder(responseWriter, request)
+
+	ctx := request.Context()
 
-	log.Trace().Msg("Validating user input")
-	errValidate := utils.ValidateStruct(&ticket)
-
-	if errValidate != nil {
-		log.Trace().Msg("Validation error")
-		if _, ok := errValidate.(*validator.InvalidValidationError); ok {
-			log.Trace().Msg("Error with validator")
-			log.Error().Str("Error message: ", errValidate.Error())
-			http.Error(responseWriter, errValidate.Error(), http.StatusInternalServerError)
-            return
-		}
-
-		log.Trace().Msg("User input error")
-		errors := make(map[string]string)
-		for _, err := range errValidate.(validator.ValidationErrors) {
-            errors[err.Field()] = fmt.Sprintf("Validation failed on '%s' tag", err.Tag())
-			log.Error().Msg(fmt.Sprintf("Validation failed on '%s' tag", err.Tag()))
-        }
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: "Failed to save ticket because didn't pass the validation",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: errors,
-		}
-
-        json.NewEncoder(responseWriter).Encode(response)
+	ticket, ok := decodeJSON[domain.Ticket](respond, request, "Failed to save ticket because didn't pass the validation")
+
+	if !ok {
 		return
 	}
 
-	log.Debug().
+	logger.Debug().
 		Int("Ticket ID: ", ticket.TicketID).
 		Int("Event ID: ", ticket.EventID).
 		Str("Name: ", ticket.Name).
@@ -103,270 +45,96 @@ func (h TicketHandler) Save(responseWriter http.ResponseWriter, request *http.Re
 		Str("Type: ", ticket.Type).
 		Msg("Continuing ticket save process")
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-
-	go func() {
-		defer close(done)
-		log.Trace().Msg("Inside goroutine trying to save")
-		savedTicket, errSave := h.usecaseTicket.Save(ctx, ticket)
-
-		if errSave != nil {
-			log.Trace().Msg("Checking error cause")
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-			
-			if errors.Is(errSave, context.DeadlineExceeded) || errors.Is(errSave, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Save error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errSave.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusCreated)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusCreated,
-			StatusDesc: http.StatusText(http.StatusCreated),
-			Message: "Created",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: savedTicket,
-		}
-
-		log.Info().Msg("Ticket created successfully and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Trace().Msg("Request completed")
+	savedTicket, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicket.Save(ctx, ticket)
+	})
+
+	if !ok {
+		return
 	}
+
+	logger.Info().Msg("Ticket created successfully and returning json")
+	respond.JSON(http.StatusCreated, "Created", savedTicket)
 }
 
 func (h TicketHandler) FindById(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket handler find by id")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	idString := request.PathValue("id")
-	log.Debug().Str("Received Id is: ", idString)
-
-	log.Trace().Msg("Trying to convert id in string to int")
-	id, errConv := strconv.Atoi(idString)
-
-	if errConv != nil {
-		log.Trace().Msg("Error happens when converting id string to int")
-		log.Error().Str("Error message: ", errConv.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: errConv.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket handler find by id")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
+
+	id, ok := pathID(respond, request)
+
+	if !ok {
 		return
 	}
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to fetch data by id")
-		defer close(done)
-		foundTicket, errFound := h.usecaseTicket.FindById(ctx, id)
-
-		if errFound != nil {
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-
-			log.Trace().Msg("Checking error cause")
-			if errors.Is(errFound, context.DeadlineExceeded) || errors.Is(errFound, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errFound.Error())
-
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Fetch error")
-				log.Error().Str("Error message: ", errFound.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errFound.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: foundTicket,
-		}
-		log.Info().Msg("Ticket fetched successfully and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-	
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	foundTicket, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicket.FindById(ctx, id)
+	})
+
+	if !ok {
+		return
 	}
+
+	logger.Info().Msg("Ticket fetched successfully and returning json")
+	respond.JSON(http.StatusOK, "OK", foundTicket)
 }
 
 func (h TicketHandler) GetAll(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket get all handler")
-	ctx, cancel := context.WithTimeout(request.Context(), 30 * time.Second)
-	defer cancel()
-
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to get all data")
-		defer close(done)
-		allTickets, err := h.usecaseTicket.GetAll(ctx)
-
-		if err != nil {
-			log.Trace().Msg("Error happens when trying to fetch data")
-			log.Error().Str("Error message: ", err.Error())
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-			response := dto.GlobalResponse {
-				StatusCode: http.StatusRequestTimeout,
-				StatusDesc: http.StatusText(http.StatusRequestTimeout),
-				Message: "Request Timed Out",
-				RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-				ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-				Data: "",
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-		
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: allTickets,
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
-		log.Info().Msg("Event fetched and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket get all handler")
+	respond := newResponder(responseWriter, request)
+	ctx := readContext(request)
+
+	allTickets, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicket.GetAll(ctx)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("Event fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", allTickets)
+}
+
+func (h TicketHandler) Update(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket handler update")
+	respond := newResponder(responseWriter, request)
+
+	ticket, ok := decodeJSON[domain.Ticket](respond, request, "Failed to update Ticket because didn't pass the validation")
+
+	if !ok {
+		return
 	}
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecaseTicket.Update(ctx, id, ticket)
+	})
+}
+
+func (h TicketHandler) Patch(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket handler patch")
+	respond := newResponder(responseWriter, request)
+
+	patch, ok := readPatch(respond, request)
+
+	if !ok {
+		return
+	}
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecaseTicket.Patch(ctx, id, patch)
+	})
+}
+
+func (h TicketHandler) Delete(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket handler delete")
+	respond := newResponder(responseWriter, request)
+
+	modifyById(respond, request, h.changeTimeout, "Deleted", func(ctx context.Context, id int) (interface{}, error) {
+		return nil, h.usecaseTicket.Delete(ctx, id)
+	})
 }
\ No newline at end of file
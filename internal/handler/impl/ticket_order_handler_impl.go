@@ -2,99 +2,57 @@ package impl
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/domain/dto"
 	"ticket_goroutine/internal/handler"
 	"ticket_goroutine/internal/usecase"
-	"ticket_goroutine/utils"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
+// preferAsync is the Prefer header value (RFC 7240) asking Save to answer 202 Accepted before the order is placed
+const preferAsync = "respond-async"
+
 type TicketOrderHandler struct {
 	usecaseTicketOrder usecase.TicketOrderUseCaseInterface
+	intake             usecase.TicketOrderIntakeInterface
+	changeTimeout      time.Duration
 }
 
-func NewTicketOrderHandler(usecaseTicketOrder usecase.TicketOrderUseCaseInterface) (handler.TicketOrderHandlerInterface) {
+func NewTicketOrderHandler(usecaseTicketOrder usecase.TicketOrderUseCaseInterface, intake usecase.TicketOrderIntakeInterface, changeTimeout time.Duration) (handler.TicketOrderHandlerInterface) {
 	return TicketOrderHandler {
 		usecaseTicketOrder: usecaseTicketOrder,
+		intake: intake,
+		changeTimeout: changeTimeout,
 	}
 }
 
 func (h TicketOrderHandler) Save(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket order handler save")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 7 * time.Second)
-	defer cancel()
-
-	var ticketOrder domain.TicketOrder
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket order handler save")
+	respond := newResponder(responseWriter, request)
 
-	log.Trace().Msg("Decoding json")
-	err := json.NewDecoder(request.Body).Decode(&ticketOrder)
+	ctx := request.Context()
 
-	if err != nil {
-		log.Trace().Msg("JSON decode error")
-		log.Error().Str("Error message: ", err.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
+	ticketOrder, ok := decodeJSON[domain.TicketOrder](respond, request, "Failed to save ticket order because didn't pass the validation")
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
+	if !ok {
 		return
 	}
 
-	log.Trace().Msg("Validating user input")
-	errValidate := utils.ValidateStruct(&ticketOrder)
+	// orders are always placed for the authenticated user, whatever UserID the body carries
+	ticketOrder.UserID, ok = callerID(respond, request)
 
-	if errValidate != nil {
-		log.Trace().Msg("Validation error")
-		if _, ok := errValidate.(*validator.InvalidValidationError); ok {
-			log.Trace().Msg("Error with validator")
-			log.Error().Str("Error message: ", errValidate.Error())
-			http.Error(responseWriter, errValidate.Error(), http.StatusInternalServerError)
-            return
-		}
-
-		log.Trace().Msg("User input error")
-		errors := make(map[string]string)
-		for _, err := range errValidate.(validator.ValidationErrors) {
-            errors[err.Field()] = fmt.Sprintf("Validation failed on '%s' tag", err.Tag())
-			log.Error().Msg(fmt.Sprintf("Validation failed on '%s' tag", err.Tag()))
-        }
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: "Failed to save ticket order because didn't pass the validation",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: errors,
-		}
-
-        json.NewEncoder(responseWriter).Encode(response)
+	if !ok {
 		return
 	}
 
-	log.Debug().
+	logger.Debug().
 		Int("Order ID: ", ticketOrder.OrderID).
 		Int("Ticket ID: ", ticketOrder.TicketID).
 		Int("User ID: ", ticketOrder.UserID).
@@ -102,296 +60,196 @@ func (h TicketOrderHandler) Save(responseWriter http.ResponseWriter, request *ht
 		Float64("Total Price: ", ticketOrder.TotalPrice).
 		Msg("Continuing ticket order save process")
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-
-	go func() {
-		defer close(done)
-		log.Trace().Msg("Inside goroutine trying to save")
-		savedTicketOrder, errSave := h.usecaseTicketOrder.Save(ctx, ticketOrder)
-
-		if errSave != nil {
-			log.Trace().Msg("Checking error cause")
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-			
-			if errors.Is(errSave, context.DeadlineExceeded) || errors.Is(errSave, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else if errSave.Error() == "not enough stock" {
-				log.Trace().Msg("Save error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusUnprocessableEntity)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusUnprocessableEntity,
-					StatusDesc: http.StatusText(http.StatusUnprocessableEntity),
-					Message: errSave.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else if errSave.Error() == "not enough balance" {
-				log.Trace().Msg("Save error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusPaymentRequired)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusPaymentRequired,
-					StatusDesc: http.StatusText(http.StatusPaymentRequired),
-					Message: errSave.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Save error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errSave.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-
-			json.NewEncoder(responseWriter).Encode(response)
+	// orders are placed by the intake's workers, clients preferring it get 202 and poll the request instead of waiting
+	if strings.Contains(request.Header.Get("Prefer"), preferAsync) {
+		// the order outlives the request, so it keeps the caller but not the request's cancellation
+		orderRequest, errSubmit := h.intake.Submit(context.WithoutCancel(request.Context()), ticketOrder)
+
+		if errSubmit != nil {
+			respond.Error(errSubmit)
 			return
 		}
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusCreated)
+		logger.Info().Msg("Ticket order queued and returning json")
+		responseWriter.Header().Set("Preference-Applied", preferAsync)
+		accepted(respond, orderRequest)
+		return
+	}
 
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusCreated,
-			StatusDesc: http.StatusText(http.StatusCreated),
-			Message: "Created",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: savedTicketOrder,
-		}
+	// the job keeps to its own timeout even once the buyer stopped waiting, so an order is never placed halfway
+	orderRequest, errSubmit := h.intake.Submit(context.WithoutCancel(ctx), ticketOrder)
+
+	if errSubmit != nil {
+		respond.Error(errSubmit)
+		return
+	}
 
-		log.Info().Msg("Ticket order created successfully and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
+	placedRequest, errWait := h.intake.Wait(ctx, orderRequest.RequestID)
+
+	// the order may still be placed after the buyer stopped waiting, so they are told where to poll for it
+	if errors.Is(errWait, domain.ErrTimeout) {
+		logger.Info().Msg(fmt.Sprintf("Ticket order request %s not finished in time, returning json to poll it", orderRequest.RequestID))
+
+		if currentRequest, errStatus := h.intake.Status(ctx, orderRequest.RequestID); errStatus == nil {
+			orderRequest = currentRequest
 		}
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Trace().Msg("Request completed")
+		accepted(respond, orderRequest)
+		return
+	}
+
+	if errWait != nil {
+		respond.Error(errWait)
+		return
+	}
+
+	// a failed order placed nothing, or gave back what it took, a timeout among them
+	if placedRequest.Failure != nil {
+		logger.Trace().Msg("Checking error cause")
+		respond.Error(placedRequest.Failure)
+		return
 	}
+
+	logger.Info().Msg("Ticket order created successfully and returning json")
+	respond.JSON(http.StatusCreated, "Created", placedRequest.Order)
 }
 
-func (h TicketOrderHandler) FindById(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket order handler find by id")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	idString := request.PathValue("id")
-	log.Debug().Str("Received Id is: ", idString)
-
-	log.Trace().Msg("Trying to convert id in string to int")
-	id, errConv := strconv.Atoi(idString)
-
-	if errConv != nil {
-		log.Trace().Msg("Error happens when converting id string to int")
-		log.Error().Str("Error message: ", errConv.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: errConv.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
+// accepted answers 202 with where to poll an order request not finished yet
+func accepted(respond *responder, orderRequest dto.OrderRequestResponse) {
+	respond.writer.Header().Set("Location", "/ticket-order/request/"+orderRequest.RequestID)
+	respond.JSON(http.StatusAccepted, "Accepted", orderRequest)
+}
+
+// GetRequest reports how far an order answered with 202 Accepted got
+func (h TicketOrderHandler) GetRequest(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket order handler get request")
+	respond := newResponder(responseWriter, request)
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
+	orderRequest, err := h.intake.Status(request.Context(), request.PathValue("id"))
+
+	if err != nil {
+		respond.Error(err)
 		return
 	}
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to fetch data by id")
-		defer close(done)
-		foundTicketOrder, errFound := h.usecaseTicketOrder.FindById(ctx, id)
-
-		if errFound != nil {
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-
-			log.Trace().Msg("Checking error cause")
-			if errors.Is(errFound, context.DeadlineExceeded) || errors.Is(errFound, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errFound.Error())
-
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Fetch error")
-				log.Error().Str("Error message: ", errFound.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errFound.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
+	if orderRequest.Failure != nil {
+		// like in error responses, the cause of an internal error is not leaked
+		orderRequest.Error = orderRequest.Failure.Error()
+
+		if statusFor(orderRequest.Failure) == http.StatusInternalServerError {
+			orderRequest.Error = http.StatusText(http.StatusInternalServerError)
 		}
+	}
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
+	respond.JSON(http.StatusOK, "OK", orderRequest)
+}
 
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: foundTicketOrder,
-		}
-		log.Info().Msg("Ticket order fetched successfully and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-	
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
+func (h TicketOrderHandler) FindById(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket order handler find by id")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	id, ok := pathID(respond, request)
+
+	if !ok {
+		return
 	}
+
+	foundTicketOrder, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicketOrder.FindById(ctx, id)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("Ticket order fetched successfully and returning json")
+	respond.JSON(http.StatusOK, "OK", foundTicketOrder)
 }
 
 func (h TicketOrderHandler) GetAll(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering ticket order get all handler")
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to get all data")
-		defer close(done)
-		allTicketOrders, err := h.usecaseTicketOrder.GetAll(ctx)
-
-		if err != nil {
-			log.Trace().Msg("Error happens when trying to fetch data")
-			log.Error().Str("Error message: ", err.Error())
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-			response := dto.GlobalResponse {
-				StatusCode: http.StatusRequestTimeout,
-				StatusDesc: http.StatusText(http.StatusRequestTimeout),
-				Message: "Request Timed Out",
-				RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-				ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-				Data: "",
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-		
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: allTicketOrders,
-		}
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket order get all handler")
+	respond := newResponder(responseWriter, request)
+	ctx := readContext(request)
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
-		log.Info().Msg("Event fetched and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
+	allTicketOrders, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicketOrder.GetAll(ctx)
+	})
 
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	if !ok {
+		return
 	}
+
+	logger.Info().Msg("Event fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", allTicketOrders)
+}
+
+func (h TicketOrderHandler) GetByOrganizer(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering ticket order handler get by organizer")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
+
+	organizerID, ok := pathID(respond, request)
+
+	if !ok {
+		return
+	}
+
+	sales, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecaseTicketOrder.GetByOrganizer(ctx, organizerID)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("Ticket orders fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", sales)
+}
+
+func (h TicketOrderHandler) Update(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket order handler update")
+	respond := newResponder(responseWriter, request)
+
+	ticketOrder, ok := decodeJSON[domain.TicketOrder](respond, request, "Failed to update ticket order because didn't pass the validation")
+
+	if !ok {
+		return
+	}
+
+	// an order keeps its owner whatever UserID the body carries, the use case checks the caller against the stored order
+	ticketOrder.UserID = 0
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecaseTicketOrder.Update(ctx, id, ticketOrder)
+	})
+}
+
+func (h TicketOrderHandler) Patch(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket order handler patch")
+	respond := newResponder(responseWriter, request)
+
+	patch, ok := readPatch(respond, request)
+
+	if !ok {
+		return
+	}
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecaseTicketOrder.Patch(ctx, id, patch)
+	})
+}
+
+func (h TicketOrderHandler) Delete(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering ticket order handler delete")
+	respond := newResponder(responseWriter, request)
+
+	modifyById(respond, request, h.changeTimeout, "Deleted", func(ctx context.Context, id int) (interface{}, error) {
+		return nil, h.usecaseTicketOrder.Delete(ctx, id)
+	})
 }
\ No newline at end of file
@@ -2,99 +2,41 @@ package impl
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 	"ticket_goroutine/internal/domain"
-	"ticket_goroutine/internal/domain/dto"
 	"ticket_goroutine/internal/handler"
 	"ticket_goroutine/internal/usecase"
-	"ticket_goroutine/utils"
 	"time"
 
-	"github.com/go-playground/validator/v10"
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type UserHandler struct {
-	usecase usecase.UserUseCaseInterface
+	usecase       usecase.UserUseCaseInterface
+	changeTimeout time.Duration
 }
 
-func NewUserHandler(usecase usecase.UserUseCaseInterface) (handler.UserHandlerInterface) {
+func NewUserHandler(usecase usecase.UserUseCaseInterface, changeTimeout time.Duration) (handler.UserHandlerInterface) {
 	return UserHandler {
 		usecase: usecase,
+		changeTimeout: changeTimeout,
 	}
 }
 
 func (h UserHandler) Save(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering user handler save")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	var user domain.User
-
-	log.Trace().Msg("Decoding json")
-	err := json.NewDecoder(request.Body).Decode(&user)
-
-	if err != nil {
-		log.Trace().Msg("JSON decode error")
-		log.Error().Str("Error message: ", err.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: err.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
-		return
-	}
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering user handler save")
+	respond := newResponder(responseWriter, request)
+
+	ctx := request.Context()
 
-	log.Trace().Msg("Validating user input")
-	errValidate := utils.ValidateStruct(&user)
-
-	if errValidate != nil {
-		log.Trace().Msg("Validation error")
-		if _, ok := errValidate.(*validator.InvalidValidationError); ok {
-			log.Trace().Msg("Error with validator")
-			log.Error().Str("Error message: ", errValidate.Error())
-			http.Error(responseWriter, errValidate.Error(), http.StatusInternalServerError)
-            return
-		}
-
-		log.Trace().Msg("User input error")
-		errors := make(map[string]string)
-		for _, err := range errValidate.(validator.ValidationErrors) {
-            errors[err.Field()] = fmt.Sprintf("Validation failed on '%s' tag", err.Tag())
-			log.Error().Msg(fmt.Sprintf("Validation failed on '%s' tag", err.Tag()))
-        }
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: "Failed to save user because didn't pass the validation",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: errors,
-		}
-
-        json.NewEncoder(responseWriter).Encode(response)
+	user, ok := decodeJSON[domain.User](respond, request, "Failed to save user because didn't pass the validation")
+
+	if !ok {
 		return
 	}
 
-	log.Debug().
+	logger.Debug().
 		Int("User ID: ", user.UserID).
 		Str("Email: ", user.Email).
 		Str("Name: ", user.Name).
@@ -102,270 +44,96 @@ func (h UserHandler) Save(responseWriter http.ResponseWriter, request *http.Requ
 		Float64("Balance: ", user.Balance).
 		Msg("Continuing user save process")
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-
-	go func() {
-		defer close(done)
-		log.Trace().Msg("Inside goroutine trying to save")
-		savedUser, errSave := h.usecase.Save(ctx, user)
-
-		if errSave != nil {
-			log.Trace().Msg("Checking error cause")
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-			
-			if errors.Is(errSave, context.DeadlineExceeded) || errors.Is(errSave, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Save error")
-				log.Error().Str("Error message: ", errSave.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errSave.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusCreated)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusCreated,
-			StatusDesc: http.StatusText(http.StatusCreated),
-			Message: "Created",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: savedUser,
-		}
-
-		log.Info().Msg("User created successfully and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Trace().Msg("Request completed")
+	savedUser, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.Save(ctx, user)
+	})
+
+	if !ok {
+		return
 	}
+
+	logger.Info().Msg("User created successfully and returning json")
+	respond.JSON(http.StatusCreated, "Created", savedUser)
 }
 
 func (h UserHandler) FindById(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering user handler find by id")
-
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	idString := request.PathValue("id")
-	log.Debug().Str("Received Id is: ", idString)
-
-	log.Trace().Msg("Trying to convert id in string to int")
-	id, errConv := strconv.Atoi(idString)
-
-	if errConv != nil {
-		log.Trace().Msg("Error happens when converting id string to int")
-		log.Error().Str("Error message: ", errConv.Error())
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusBadRequest,
-			StatusDesc: http.StatusText(http.StatusBadRequest),
-			Message: errConv.Error(),
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-        responseWriter.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(responseWriter).Encode(response)
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering user handler find by id")
+	respond := newResponder(responseWriter, request)
+
+	ctx := readContext(request)
+
+	id, ok := pathID(respond, request)
+
+	if !ok {
 		return
 	}
 
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to fetch data by id")
-		defer close(done)
-		foundUser, errFound := h.usecase.FindById(ctx, id)
-
-		if errFound != nil {
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			var response dto.GlobalResponse
-
-			log.Trace().Msg("Checking error cause")
-			if errors.Is(errFound, context.DeadlineExceeded) || errors.Is(errFound, context.Canceled) {
-				log.Trace().Msg("Timeout error")
-				log.Error().Str("Error message: ", errFound.Error())
-
-				responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusRequestTimeout,
-					StatusDesc: http.StatusText(http.StatusRequestTimeout),
-					Message: "Request Timed Out",
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			} else {
-				log.Trace().Msg("Fetch error")
-				log.Error().Str("Error message: ", errFound.Error())
-				responseWriter.WriteHeader(http.StatusBadRequest)
-
-				response = dto.GlobalResponse {
-					StatusCode: http.StatusBadRequest,
-					StatusDesc: http.StatusText(http.StatusBadRequest),
-					Message: errFound.Error(),
-					RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-					ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-					Data: "",
-				}
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: foundUser,
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
-		log.Info().Msg("User fetched and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-	
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	foundUser, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.FindById(ctx, id)
+	})
+
+	if !ok {
+		return
 	}
+
+	logger.Info().Msg("User fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", foundUser)
 }
 
 func (h UserHandler) GetAll(responseWriter http.ResponseWriter, request *http.Request) {
-	log.Trace().Msg("Entering user get all handler")
-	ctx, cancel := context.WithTimeout(request.Context(), 2 * time.Second)
-	defer cancel()
-
-	done := make(chan struct{})
-	log.Info().Msg("Channel created")
-	go func() {
-		log.Trace().Msg("Inside goroutine trying to get all data")
-		defer close(done)
-		allUsers, err := h.usecase.GetAll(ctx)
-
-		if err != nil {
-			log.Trace().Msg("Error happens when trying to fetch data")
-			log.Error().Str("Error message: ", err.Error())
-			responseWriter.Header().Set("Content-Type", "application/json")
-			responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-
-			responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-			response := dto.GlobalResponse {
-				StatusCode: http.StatusRequestTimeout,
-				StatusDesc: http.StatusText(http.StatusRequestTimeout),
-				Message: "Request Timed Out",
-				RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-				ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-				Data: "",
-			}
-			json.NewEncoder(responseWriter).Encode(response)
-			return
-		}
-		
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusOK,
-			StatusDesc: http.StatusText(http.StatusOK),
-			Message: "OK",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: allUsers,
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.WriteHeader(http.StatusOK)
-		log.Info().Msg("User fetched and returning json")
-		json.NewEncoder(responseWriter).Encode(response)
-	}()
-
-	select {
-	case <- ctx.Done():
-		log.Trace().Msg("Request timeout channel")
-		responseWriter.WriteHeader(http.StatusRequestTimeout)
-
-		response := dto.GlobalResponse {
-			StatusCode: http.StatusRequestTimeout,
-			StatusDesc: http.StatusText(http.StatusRequestTimeout),
-			Message: "Request Timed Out",
-			RequestCreated: time.Now().Format("2006-01-02 15:04:05"),
-			ProcessTime: time.Duration(time.Since(time.Now()).Milliseconds()),
-			Data: "",
-		}
-
-		responseWriter.Header().Set("Content-Type", "application/json")
-		responseWriter.Header().Set("X-Content-Type-Options", "nosniff")
-		json.NewEncoder(responseWriter).Encode(response)
-	case <- done:
-		log.Info().Msg("Request completed")
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering user get all handler")
+	respond := newResponder(responseWriter, request)
+	ctx := readContext(request)
+
+	allUsers, ok := await(respond, ctx, func(ctx context.Context) (interface{}, error) {
+		return h.usecase.GetAll(ctx)
+	})
+
+	if !ok {
+		return
+	}
+
+	logger.Info().Msg("User fetched and returning json")
+	respond.JSON(http.StatusOK, "OK", allUsers)
+}
+
+func (h UserHandler) Update(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering user handler update")
+	respond := newResponder(responseWriter, request)
+
+	user, ok := decodeJSON[domain.User](respond, request, "Failed to update User because didn't pass the validation")
+
+	if !ok {
+		return
 	}
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecase.Update(ctx, id, user)
+	})
+}
+
+func (h UserHandler) Patch(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering user handler patch")
+	respond := newResponder(responseWriter, request)
+
+	patch, ok := readPatch(respond, request)
+
+	if !ok {
+		return
+	}
+
+	modifyById(respond, request, h.changeTimeout, "Updated", func(ctx context.Context, id int) (interface{}, error) {
+		return h.usecase.Patch(ctx, id, patch)
+	})
+}
+
+func (h UserHandler) Delete(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering user handler delete")
+	respond := newResponder(responseWriter, request)
+
+	modifyById(respond, request, h.changeTimeout, "Deleted", func(ctx context.Context, id int) (interface{}, error) {
+		return nil, h.usecase.Delete(ctx, id)
+	})
 }
\ No newline at end of file
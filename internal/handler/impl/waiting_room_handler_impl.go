@@ -0,0 +1,113 @@
+package impl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"ticket_goroutine/internal/handler"
+	"ticket_goroutine/internal/usecase"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// streamEvery is how often a streamed position is sent again
+const streamEvery = time.Second
+
+type WaitingRoomHandler struct {
+	usecase usecase.WaitingRoomUseCaseInterface
+}
+
+func NewWaitingRoomHandler(usecase usecase.WaitingRoomUseCaseInterface) (handler.WaitingRoomHandlerInterface) {
+	return WaitingRoomHandler {
+		usecase: usecase,
+	}
+}
+
+func (h WaitingRoomHandler) Join(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering waiting room handler join")
+	respond := newResponder(responseWriter, request)
+
+	position, err := h.usecase.Join(request.Context())
+
+	if err != nil {
+		respond.Error(err)
+		return
+	}
+
+	logger.Info().Msg("Joined the waiting room and returning json")
+	respond.JSON(http.StatusCreated, "Created", position)
+}
+
+func (h WaitingRoomHandler) Position(responseWriter http.ResponseWriter, request *http.Request) {
+	zerolog.Ctx(request.Context()).Trace().Msg("Entering waiting room handler position")
+	respond := newResponder(responseWriter, request)
+
+	position, err := h.usecase.Position(request.Context(), request.PathValue("token"))
+
+	if err != nil {
+		respond.Error(err)
+		return
+	}
+
+	respond.JSON(http.StatusOK, "OK", position)
+}
+
+// Stream sends the position as server-sent events every streamEvery, until the client is admitted or disconnects
+func (h WaitingRoomHandler) Stream(responseWriter http.ResponseWriter, request *http.Request) {
+	logger := zerolog.Ctx(request.Context())
+	logger.Trace().Msg("Entering waiting room handler stream")
+	respond := newResponder(responseWriter, request)
+	token := request.PathValue("token")
+
+	// the first read answers an unknown token as a normal error, before the stream starts
+	position, err := h.usecase.Position(request.Context(), token)
+
+	if err != nil {
+		respond.Error(err)
+		return
+	}
+
+	controller := http.NewResponseController(responseWriter)
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(streamEvery)
+	defer ticker.Stop()
+
+	for {
+		data, _ := json.Marshal(position)
+
+		if _, err := fmt.Fprintf(responseWriter, "event: position\ndata: %s\n\n", data); err != nil {
+			logger.Debug().Msg(fmt.Sprintf("Waiting room stream closed: %s", err))
+			return
+		}
+
+		if err := controller.Flush(); err != nil {
+			logger.Error().Msg(fmt.Sprintf("Waiting room stream cannot be flushed: %s", err))
+			return
+		}
+
+		if position.Admitted {
+			logger.Info().Msg("Client admitted, closing waiting room stream")
+			return
+		}
+
+		select {
+		case <- request.Context().Done():
+			logger.Debug().Msg("Client left the waiting room stream")
+			return
+		case <- ticker.C:
+		}
+
+		position, err = h.usecase.Position(request.Context(), token)
+
+		if err != nil {
+			fmt.Fprintf(responseWriter, "event: error\ndata: %q\n\n", err.Error())
+			controller.Flush()
+			return
+		}
+	}
+}
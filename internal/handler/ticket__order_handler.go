@@ -8,6 +8,11 @@ type TicketOrderHandlerInterface interface {
 	TicketOrderSave
 	TicketOrderFindById
 	TicketOrderGetAll
+	TicketOrderGetByOrganizer
+	TicketOrderGetRequest
+	TicketOrderUpdate
+	TicketOrderPatch
+	TicketOrderDelete
 }
 
 type TicketOrderSave interface {
@@ -20,4 +25,24 @@ type TicketOrderFindById interface {
 
 type TicketOrderGetAll interface {
 	GetAll(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketOrderGetByOrganizer interface {
+	GetByOrganizer(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketOrderGetRequest interface {
+	GetRequest(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketOrderUpdate interface {
+	Update(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketOrderPatch interface {
+	Patch(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketOrderDelete interface {
+	Delete(responseWritter http.ResponseWriter, request *http.Request)
 }
\ No newline at end of file
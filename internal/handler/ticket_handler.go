@@ -8,6 +8,9 @@ type TicketHandlerInterface interface {
 	TicketSave
 	TicketFindById
 	TicketGetAll
+	TicketUpdate
+	TicketPatch
+	TicketDelete
 }
 
 type TicketSave interface {
@@ -20,4 +23,16 @@ type TicketFindById interface {
 
 type TicketGetAll interface {
 	GetAll(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketUpdate interface {
+	Update(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketPatch interface {
+	Patch(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type TicketDelete interface {
+	Delete(responseWritter http.ResponseWriter, request *http.Request)
 }
\ No newline at end of file
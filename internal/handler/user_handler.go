@@ -8,6 +8,9 @@ type UserHandlerInterface interface {
 	UserSave
 	UserFindById
 	UserGetAll
+	UserUpdate
+	UserPatch
+	UserDelete
 }
 
 type UserSave interface {
@@ -20,4 +23,16 @@ type UserFindById interface {
 
 type UserGetAll interface {
 	GetAll(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type UserUpdate interface {
+	Update(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type UserPatch interface {
+	Patch(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type UserDelete interface {
+	Delete(responseWritter http.ResponseWriter, request *http.Request)
 }
\ No newline at end of file
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+)
+
+type WaitingRoomHandlerInterface interface {
+	WaitingRoomJoin
+	WaitingRoomPosition
+	WaitingRoomStream
+}
+
+type WaitingRoomJoin interface {
+	Join(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type WaitingRoomPosition interface {
+	Position(responseWritter http.ResponseWriter, request *http.Request)
+}
+
+type WaitingRoomStream interface {
+	Stream(responseWritter http.ResponseWriter, request *http.Request)
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+	"ticket_goroutine/internal/domain"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type responseWriterWrap struct {
+	http.ResponseWriter
+	statusCode  int
+	bytes       int
+	wroteHeader bool
+}
+
+func (rw *responseWriterWrap) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriterWrap) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection's writer, to flush streamed responses
+func (rw *responseWriterWrap) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// accessEntry collects what the layers below learn about a request, the route it matched
+// and who made it, for the access log line written once the response is done
+type accessEntry struct {
+	route  string
+	caller *domain.Caller
+}
+
+type accessEntryKey struct{}
+
+func entryFromContext(ctx context.Context) *accessEntry {
+	entry, _ := ctx.Value(accessEntryKey{}).(*accessEntry)
+	return entry
+}
+
+// withEntry gives the request an entry for the layers below to fill, or the one an outer middleware already gave it
+func withEntry(r *http.Request) (*http.Request, *accessEntry) {
+	if entry := entryFromContext(r.Context()); entry != nil {
+		return r, entry
+	}
+
+	entry := &accessEntry{}
+	return r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)), entry
+}
+
+// AccessLog writes one line per request to logger, with its method, route pattern, status, size, duration,
+// client, caller and trace. Requests to skipPaths, such as health checks polled every few seconds, are not logged.
+// It must come after RequestID and Trace to log the request and trace IDs.
+func AccessLog(logger zerolog.Logger, skipPaths []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(skipPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			responseWrap := responseWriterWrap{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			r, entry := withEntry(r)
+			start := time.Now()
+
+			next.ServeHTTP(&responseWrap, r)
+
+			event := logger.Info().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Int("status", responseWrap.statusCode).
+				Int("bytes", responseWrap.bytes).
+				Dur("duration_ms", time.Since(start)).
+				Str("remote_ip", remoteIP(r)).
+				Str("user_agent", r.UserAgent())
+
+			if entry.route != "" {
+				event = event.Str("route", entry.route)
+			}
+
+			if requestID := w.Header().Get(RequestIDHeader); requestID != "" {
+				event = event.Str("request_id", requestID)
+			}
+
+			if traceID := traceID(r); traceID != "" {
+				event = event.Str("trace_id", traceID)
+			}
+
+			if entry.caller != nil {
+				event = event.Int("user_id", entry.caller.UserID)
+
+				if entry.caller.APIKeyID != 0 {
+					event = event.Int("api_key_id", entry.caller.APIKeyID)
+				}
+			}
+
+			event.Msg("access")
+		})
+	}
+}
+
+// routed records the pattern of the route a request matched, the path alone would split
+// /event/1 and /event/2 into different routes
+func routed(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry := entryFromContext(r.Context()); entry != nil {
+			entry.route = pattern
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// noteCaller records who made the request as soon as they are authenticated, so requests
+// they turn out not to be allowed to make are logged under them too
+func noteCaller(r *http.Request, caller domain.Caller) {
+	if entry := entryFromContext(r.Context()); entry != nil {
+		entry.caller = &caller
+	}
+}
+
+// remoteIP is the address of the connection, X-Forwarded-For is not trusted because any client can set it
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/provider/auth"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var errMissingToken = fmt.Errorf("missing bearer token or api key: %w", domain.ErrUnauthorized)
+
+// apiKeyHeader carries the API keys of partner backends, which call without logging in
+const apiKeyHeader = "X-API-Key"
+
+// KeyVerifier resolves API keys into the caller they act for and records every use of them
+type KeyVerifier interface {
+	Verify(ctx context.Context, key string) (domain.Caller, error)
+	RecordUsage(ctx context.Context, usage domain.APIKeyUsage) error
+}
+
+// Guard builds the per route permission middlewares. Requests without a valid
+// "Authorization: Bearer" access token or API key are rejected with domain.ErrUnauthorized,
+// callers without the needed role or scope with domain.ErrForbidden, both answered by reject.
+type Guard struct {
+	tokens *auth.TokenIssuer
+	keys   KeyVerifier
+	reject func(http.ResponseWriter, *http.Request, error)
+}
+
+func NewGuard(tokens *auth.TokenIssuer, keys KeyVerifier, reject func(http.ResponseWriter, *http.Request, error)) *Guard {
+	return &Guard{
+		tokens: tokens,
+		keys:   keys,
+		reject: reject,
+	}
+}
+
+// Authenticated lets through any user with a valid access token, whatever their role
+func (g *Guard) Authenticated() Middleware {
+	return g.Allow(domain.Roles...)
+}
+
+// Allow lets through users with one of roles, API keys are never let through
+func (g *Guard) Allow(roles ...domain.Role) Middleware {
+	return g.require(func(caller domain.Caller, r *http.Request) bool {
+		return caller.Is(roles...)
+	})
+}
+
+// AllowKey lets through users with one of roles, and API keys granted scope
+func (g *Guard) AllowKey(scope string, roles ...domain.Role) Middleware {
+	return g.require(func(caller domain.Caller, r *http.Request) bool {
+		return caller.Is(roles...) || caller.Has(scope)
+	})
+}
+
+// SelfOr lets through the user whose ID is the path value name, and users with one of roles
+func (g *Guard) SelfOr(name string, roles ...domain.Role) Middleware {
+	return g.require(func(caller domain.Caller, r *http.Request) bool {
+		id, err := strconv.Atoi(r.PathValue(name))
+		return (err == nil && caller.APIKeyID == 0 && id == caller.UserID) || caller.Is(roles...)
+	})
+}
+
+// Optional authenticates requests that carry a token and lets anonymous ones through,
+// for public routes that behave differently for some callers
+func (g *Guard) Optional() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, err := g.authenticate(r)
+
+			if errors.Is(err, errMissingToken) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err != nil {
+				g.reject(w, r, err)
+				return
+			}
+
+			noteCaller(r, caller)
+			next.ServeHTTP(w, r.WithContext(domain.WithCaller(r.Context(), caller)))
+		})
+	}
+}
+
+func (g *Guard) require(permitted func(domain.Caller, *http.Request) bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, err := g.authenticate(r)
+
+			if errors.Is(err, errMissingToken) {
+				zerolog.Ctx(r.Context()).Warn().Msg(fmt.Sprintf("Missing bearer token on %s %s", r.Method, r.URL.Path))
+			}
+
+			if err != nil {
+				g.reject(w, r, err)
+				return
+			}
+
+			noteCaller(r, caller)
+
+			if !permitted(caller, r) {
+				who := string(caller.Role)
+
+				if caller.APIKeyID != 0 {
+					who = fmt.Sprintf("api key %d", caller.APIKeyID)
+				}
+
+				zerolog.Ctx(r.Context()).Warn().Msg(fmt.Sprintf("User with ID %d as %s is not allowed to %s %s", caller.UserID, who, r.Method, r.URL.Path))
+				g.reject(w, r, fmt.Errorf("%s is not allowed to %s %s: %w", who, r.Method, r.URL.Path, domain.ErrForbidden))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithCaller(r.Context(), caller)))
+		})
+	}
+}
+
+func (g *Guard) authenticate(r *http.Request) (domain.Caller, error) {
+	logger := zerolog.Ctx(r.Context())
+
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return g.authenticateKey(r, key)
+	}
+
+	header := r.Header.Get("Authorization")
+
+	// not logged here, anonymous requests are expected on optional routes
+	if header == "" {
+		return domain.Caller{}, errMissingToken
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return domain.Caller{}, fmt.Errorf("authorization header is not a bearer token: %w", domain.ErrUnauthorized)
+	}
+
+	identity, err := g.tokens.Parse(token, auth.AccessToken)
+
+	if err != nil {
+		logger.Warn().Msg(fmt.Sprintf("Access token rejected on %s %s: %s", r.Method, r.URL.Path, err))
+		return domain.Caller{}, fmt.Errorf("%w: %w", domain.ErrUnauthorized, err)
+	}
+
+	logger.Debug().Msg(fmt.Sprintf("Request authenticated as user with ID %d as %s", identity.UserID, identity.Role))
+	return domain.Caller{UserID: identity.UserID, Role: domain.Role(identity.Role)}, nil
+}
+
+// authenticateKey verifies an API key and records the request against it, whether it is then permitted or not
+func (g *Guard) authenticateKey(r *http.Request, key string) (domain.Caller, error) {
+	logger := zerolog.Ctx(r.Context())
+	caller, err := g.keys.Verify(r.Context(), key)
+
+	if err != nil {
+		logger.Warn().Msg(fmt.Sprintf("API key rejected on %s %s: %s", r.Method, r.URL.Path, err))
+		return domain.Caller{}, err
+	}
+
+	usage := domain.APIKeyUsage{
+		KeyID:      caller.APIKeyID,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+		UsedAt:     time.Now(),
+	}
+
+	if err := g.keys.RecordUsage(r.Context(), usage); err != nil {
+		logger.Error().Msg(fmt.Sprintf("Recording usage of api key %d failed: %s", caller.APIKeyID, err))
+	}
+
+	logger.Debug().Msg(fmt.Sprintf("Request authenticated with api key %d for user with ID %d", caller.APIKeyID, caller.UserID))
+	return caller, nil
+}
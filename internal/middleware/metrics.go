@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"ticket_goroutine/internal/provider/metrics"
+	"time"
+)
+
+// Metrics counts every request and how long it took, by the route pattern it matched
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responseWrap := responseWriterWrap{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		r, entry := withEntry(r)
+		start := time.Now()
+
+		next.ServeHTTP(&responseWrap, r)
+
+		metrics.ObserveRequest(r.Method, entry.route, responseWrap.statusCode, time.Since(start))
+	})
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Limit lets a client make Requests requests per Per, in a burst or spread out
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Decision is the state of a client's bucket after asking for one request
+type Decision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait for the next token
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets. MemoryRateLimitStore is enough for a single instance,
+// instances behind a load balancer need a shared store to enforce one limit between them.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+}
+
+// RateLimiter builds the per route rate limiting middlewares. Clients are told apart by API key,
+// then user, then IP address, so it must come after the route's Guard middleware to see the caller.
+type RateLimiter struct {
+	store  RateLimitStore
+	reject func(http.ResponseWriter, *http.Request, error)
+}
+
+func NewRateLimiter(store RateLimitStore, reject func(http.ResponseWriter, *http.Request, error)) *RateLimiter {
+	return &RateLimiter{
+		store:  store,
+		reject: reject,
+	}
+}
+
+// Limit allows each client limit on the routes sharing name, rejecting the rest with domain.ErrTooManyRequests
+func (rl *RateLimiter) Limit(name string, limit Limit) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r)
+			decision, err := rl.store.Take(r.Context(), name+"|"+client, limit, time.Now())
+
+			if err != nil {
+				// a broken store must not take the routes down with it
+				zerolog.Ctx(r.Context()).Error().Msg(fmt.Sprintf("Rate limit store failed for %s on %s, letting the request through: %s", client, name, err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+
+			if !decision.Allowed {
+				zerolog.Ctx(r.Context()).Warn().Msg(fmt.Sprintf("Rate limit of %d per %s on %s reached by %s", limit.Requests, limit.Per, name, client))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(decision.RetryAfter)))
+				rl.reject(w, r, fmt.Errorf("rate limit of %d requests per %s reached: %w", limit.Requests, limit.Per, domain.ErrTooManyRequests))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies who is calling, anonymous clients by the IP of their connection
+func clientKey(r *http.Request) string {
+	if caller, ok := domain.CallerFromContext(r.Context()); ok {
+		if caller.APIKeyID != 0 {
+			return fmt.Sprintf("api_key:%d", caller.APIKeyID)
+		}
+
+		return fmt.Sprintf("user:%d", caller.UserID)
+	}
+
+	return "ip:" + remoteIP(r)
+}
+
+// seconds rounds up, so clients waiting that long never come back too early
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// MemoryRateLimitStore keeps the buckets of a single instance in memory, dropping the ones that refilled
+type MemoryRateLimitStore struct {
+	mtx       sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// sweepEvery is how often buckets that are full again, and so no different from a new one, are dropped
+const sweepEvery = time.Minute
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*bucket{},
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepEvery {
+		s.sweep(now)
+	}
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Per / time.Duration(limit.Requests)
+	b, ok := s.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	decision := Decision{Allowed: b.tokens >= 1}
+
+	if decision.Allowed {
+		b.tokens--
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	decision.Remaining = int(b.tokens)
+	decision.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+	b.full = now.Add(decision.Reset)
+
+	return decision, nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"ticket_goroutine/utils"
+)
+
+// Recover answers a request whose handler panicked with a 500 through reject, instead of net/http dropping
+// the connection, and logs the panic with its stack
+func Recover(reject func(http.ResponseWriter, *http.Request, error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+
+				if recovered == nil {
+					return
+				}
+
+				// net/http's own way of aborting a response, it must reach the server to do so
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				err := utils.Recovered(recovered)
+				err.Log(r)
+				reject(w, r, err)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
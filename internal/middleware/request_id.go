@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader carries the ID tying together every log line of one request
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps IDs sent by clients or proxies short and free of anything that would garble a log line
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID takes the X-Request-ID a client or proxy sent, or makes one up, echoes it in the response and
+// puts a logger carrying it in the request context, for the layers below to log through zerolog.Ctx
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+		logger := log.With().Str("request_id", requestID).Logger()
+
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context())))
+	})
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+
+	// crypto/rand doesn't fail on the supported platforms, an ID of zeros would still be served
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
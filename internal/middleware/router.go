@@ -1,19 +1,26 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type Router struct {
 	router *http.ServeMux
+	// timeout bounds every route that doesn't set its own with Timeout
+	timeout time.Duration
 }
 
 type fn func(http.ResponseWriter, *http.Request)
 
-func NewRouter() *Router {
+type routeTimeoutKey struct{}
+
+func NewRouter(timeout time.Duration) *Router {
 	return &Router{
-		router: http.NewServeMux(),
+		router:  http.NewServeMux(),
+		timeout: timeout,
 	}
 }
 
@@ -21,6 +28,55 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.router.ServeHTTP(w, req)
 }
 
-func (r *Router) AddRoute(method string, url string, handler fn) {
-	r.router.HandleFunc(fmt.Sprintf("%s %s", method, url), handler)
-}
\ No newline at end of file
+// AddRoute registers handler for method and url, wrapped by the route's own middlewares in the order given
+func (r *Router) AddRoute(method string, url string, handler fn, middlewares ...Middleware) {
+	pattern := fmt.Sprintf("%s %s", method, url)
+	r.router.Handle(pattern, routed(pattern, CreateStack(middlewares...)(r.deadline(traced(pattern, http.HandlerFunc(handler))))))
+}
+
+// Timeout gives a route its own time limit instead of the router's, a timeout of 0 lifts the limit for
+// routes that stream. The limit starts when the handler is reached, after the route's other middlewares.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), routeTimeoutKey{}, timeout)))
+		})
+	}
+}
+
+// CancelOn ends the request's context once done is, for routes streaming until the client leaves
+// that would otherwise keep the server from shutting down
+func CancelOn(done context.Context) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, cancel := context.WithCancel(req.Context())
+			defer cancel()
+
+			stop := context.AfterFunc(done, cancel)
+			defer stop()
+
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// deadline sets the context deadline the handlers give up at, from the route's Timeout or the router's
+func (r *Router) deadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		timeout, ok := req.Context().Value(routeTimeoutKey{}).(time.Duration)
+
+		if !ok {
+			timeout = r.timeout
+		}
+
+		if timeout <= 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"ticket_goroutine/internal/provider/tracing"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts the span of every request, continuing the trace of callers that sent a W3C traceparent header,
+// and adds the trace ID to the request's logger. It must come after RequestID to extend its logger.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.StartServer(r.Context(), r.Header, r.Method,
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("client.address", remoteIP(r)),
+			attribute.String("request_id", w.Header().Get(RequestIDHeader)),
+		)
+		defer span.End()
+
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			logger := zerolog.Ctx(ctx).With().Str("trace_id", spanContext.TraceID().String()).Logger()
+			ctx = logger.WithContext(ctx)
+		}
+
+		responseWrap := responseWriterWrap{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+		r, entry := withEntry(r.WithContext(ctx))
+
+		next.ServeHTTP(&responseWrap, r)
+
+		// spans are named after the route, the path alone would make one name per ID
+		if entry.route != "" {
+			span.SetName(entry.route)
+			span.SetAttributes(attribute.String("http.route", entry.route))
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", responseWrap.statusCode))
+
+		if responseWrap.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(responseWrap.statusCode))
+		}
+	})
+}
+
+// traced gives the handler of a route its own span, apart from the route's middlewares
+func traced(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "handler "+pattern)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// traceID is the ID of the trace the request is part of, empty when it is not traced
+func traceID(r *http.Request) string {
+	spanContext := trace.SpanContextFromContext(r.Context())
+
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
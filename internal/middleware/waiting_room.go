@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"ticket_goroutine/internal/domain"
+
+	"github.com/rs/zerolog"
+)
+
+// queueTokenHeader carries the token a client got from joining the waiting room
+const queueTokenHeader = "X-Queue-Token"
+
+// Admitter tells whether the waiting room let a user's queue token through
+type Admitter interface {
+	Admitted(token string, userID int) bool
+}
+
+// RequireAdmission only lets through callers whose X-Queue-Token was admitted by the waiting room,
+// rejecting the rest with domain.ErrNotAdmitted. It must come after the route's Guard middleware.
+func RequireAdmission(room Admitter, reject func(http.ResponseWriter, *http.Request, error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			caller, _ := domain.CallerFromContext(r.Context())
+			token := r.Header.Get(queueTokenHeader)
+
+			if token == "" || !room.Admitted(token, caller.UserID) {
+				zerolog.Ctx(r.Context()).Warn().Msg(fmt.Sprintf("User with ID %d tried to %s %s without being admitted", caller.UserID, r.Method, r.URL.Path))
+				reject(w, r, fmt.Errorf("%w, join with POST /waiting-room/ and send the admitted token as %s", domain.ErrNotAdmitted, queueTokenHeader))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
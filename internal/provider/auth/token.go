@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// Token types, a refresh token is never accepted where an access token is expected and the other way around
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type claims struct {
+	TokenType string `json:"token_type"`
+	Role      string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// Identity is who a verified token was issued to
+type Identity struct {
+	UserID int
+	Role   string
+}
+
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresIn  time.Duration
+	RefreshExpiresIn time.Duration
+}
+
+// TokenIssuer signs and verifies HS256 tokens whose subject is the user ID
+type TokenIssuer struct {
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenIssuer signs with secret, or with a random key when secret is empty
+func NewTokenIssuer(secret string, issuer string, accessTTL time.Duration, refreshTTL time.Duration) *TokenIssuer {
+	key := []byte(secret)
+
+	if len(key) == 0 {
+		log.Warn().Msg("No JWT secret configured, using a random one so issued tokens won't survive a restart")
+		key = make([]byte, 32)
+
+		if _, err := rand.Read(key); err != nil {
+			panic(fmt.Sprintf("generating JWT secret failed: %s", err))
+		}
+	}
+
+	return &TokenIssuer{
+		secret:     key,
+		issuer:     issuer,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Issue signs a new access and refresh token pair, the role is fixed until the tokens are refreshed
+func (t *TokenIssuer) Issue(identity Identity) (TokenPair, error) {
+	accessToken, err := t.sign(identity, AccessToken, t.accessTTL)
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	refreshToken, err := t.sign(identity, RefreshToken, t.refreshTTL)
+
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresIn:  t.accessTTL,
+		RefreshExpiresIn: t.refreshTTL,
+	}, nil
+}
+
+func (t *TokenIssuer) sign(identity Identity, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		TokenType: tokenType,
+		Role:      identity.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   strconv.Itoa(identity.UserID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+
+	return token.SignedString(t.secret)
+}
+
+// Parse verifies token and returns who it was issued to, any failure wraps ErrInvalidToken
+func (t *TokenIssuer) Parse(token string, tokenType string) (Identity, error) {
+	var parsed claims
+
+	_, err := jwt.ParseWithClaims(token, &parsed, func(*jwt.Token) (interface{}, error) {
+		return t.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithExpirationRequired(),
+	)
+
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if parsed.TokenType != tokenType {
+		return Identity{}, fmt.Errorf("%w: expected token type %s", ErrInvalidToken, tokenType)
+	}
+
+	userID, err := strconv.Atoi(parsed.Subject)
+
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: malformed subject", ErrInvalidToken)
+	}
+
+	return Identity{UserID: userID, Role: parsed.Role}, nil
+}
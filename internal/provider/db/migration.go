@@ -0,0 +1,306 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator loads the embedded migrations for the given database ("postgresql" or "sqlite")
+func NewMigrator(database *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := loadMigrations(dialect)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         database,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, path.Join("migrations", dialect))
+
+	if err != nil {
+		return nil, fmt.Errorf("no migrations found for database %s", dialect)
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		matches := migrationFileName.FindStringSubmatch(entry.Name())
+
+		if matches == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		content, err := fs.ReadFile(migrationFiles, path.Join("migrations", dialect, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+
+		if !exists {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s is missing its up or down file", migration.Version, migration.Name)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (m *Migrator) placeholder(n int) string {
+	if m.dialect == "sqlite" {
+		return "?"
+	}
+
+	return fmt.Sprintf("$%d", n)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`
+
+	_, err := m.db.ExecContext(ctx, query)
+	return err
+}
+
+// tableExists tells whether schema_migrations was created yet, without creating it
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	query := `SELECT to_regclass('schema_migrations') IS NOT NULL`
+
+	if m.dialect == "sqlite" {
+		query = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`
+	}
+
+	var exists bool
+	err := m.db.QueryRowContext(ctx, query).Scan(&exists)
+	return exists, err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	return m.readApplied(ctx)
+}
+
+func (m *Migrator) readApplied(ctx context.Context) (map[int]time.Time, error) {
+	res, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	applied := map[int]time.Time{}
+
+	for res.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := res.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, res.Err()
+}
+
+// Up applies every migration that has not been applied yet, in version order
+func (m *Migrator) Up(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		if _, exists := applied[migration.Version]; exists {
+			continue
+		}
+
+		log.Info().Msg(fmt.Sprintf("Applying migration %04d_%s", migration.Version, migration.Name))
+
+		record := fmt.Sprintf("INSERT INTO schema_migrations (version, name, applied_at) VALUES (%s, %s, %s)", m.placeholder(1), m.placeholder(2), m.placeholder(3))
+
+		if err := m.run(ctx, migration.Up, record, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	log.Info().Msg("Database schema is up to date")
+	return nil
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	applied, err := m.applied(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := m.migrations[i]
+
+		if _, exists := applied[migration.Version]; !exists {
+			continue
+		}
+
+		log.Info().Msg(fmt.Sprintf("Reverting migration %04d_%s", migration.Version, migration.Name))
+
+		record := fmt.Sprintf("DELETE FROM schema_migrations WHERE version=%s", m.placeholder(1))
+
+		if err := m.run(ctx, migration.Down, record, migration.Version); err != nil {
+			return fmt.Errorf("migration %04d_%s revert failed: %w", migration.Version, migration.Name, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listOfStatus := make([]MigrationStatus, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		appliedAt, exists := applied[migration.Version]
+
+		listOfStatus = append(listOfStatus, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   exists,
+			AppliedAt: appliedAt,
+		})
+	}
+
+	return listOfStatus, nil
+}
+
+// Pending counts the known migrations that have not been applied yet. It only reads, so it can back
+// the readiness check, and counts every migration as pending while schema_migrations doesn't exist.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	exists, err := m.tableExists(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		return len(m.migrations), nil
+	}
+
+	applied, err := m.readApplied(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+
+	for _, migration := range m.migrations {
+		if _, exists := applied[migration.Version]; !exists {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) run(ctx context.Context, script string, record string, args ...any) error {
+	trx, err := m.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer trx.Rollback()
+
+	if _, err := trx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := trx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return trx.Commit()
+}
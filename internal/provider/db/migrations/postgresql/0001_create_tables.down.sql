@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ticket_order;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS events;
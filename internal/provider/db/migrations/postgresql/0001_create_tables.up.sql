@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS events (
+	event_id SERIAL PRIMARY KEY,
+	event_name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tickets (
+	ticket_id SERIAL PRIMARY KEY,
+	event_id INTEGER NOT NULL REFERENCES events (event_id),
+	name VARCHAR(255) NOT NULL,
+	price NUMERIC(15, 2) NOT NULL,
+	stock INTEGER NOT NULL,
+	type VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id SERIAL PRIMARY KEY,
+	email VARCHAR(255) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	phone_number VARCHAR(20) NOT NULL,
+	balance NUMERIC(15, 2) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ticket_order (
+	order_id SERIAL PRIMARY KEY,
+	ticket_id INTEGER NOT NULL REFERENCES tickets (ticket_id),
+	user_id INTEGER NOT NULL REFERENCES users (user_id),
+	amount INTEGER NOT NULL,
+	total_price NUMERIC(15, 2) NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE tickets DROP COLUMN version;
+ALTER TABLE events DROP COLUMN version;
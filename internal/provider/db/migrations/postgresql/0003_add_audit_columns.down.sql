@@ -0,0 +1,27 @@
+ALTER TABLE ticket_order
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by,
+	DROP COLUMN deleted_at,
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at;
+
+ALTER TABLE users
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by,
+	DROP COLUMN deleted_at,
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at;
+
+ALTER TABLE tickets
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by,
+	DROP COLUMN deleted_at,
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at;
+
+ALTER TABLE events
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by,
+	DROP COLUMN deleted_at,
+	DROP COLUMN updated_at,
+	DROP COLUMN created_at;
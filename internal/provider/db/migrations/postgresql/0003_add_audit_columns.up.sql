@@ -0,0 +1,27 @@
+ALTER TABLE events
+	ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN deleted_at TIMESTAMP NULL,
+	ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT 'system';
+
+ALTER TABLE tickets
+	ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN deleted_at TIMESTAMP NULL,
+	ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT 'system';
+
+ALTER TABLE users
+	ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN deleted_at TIMESTAMP NULL,
+	ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT 'system';
+
+ALTER TABLE ticket_order
+	ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ADD COLUMN deleted_at TIMESTAMP NULL,
+	ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+	ADD COLUMN updated_by VARCHAR(255) NOT NULL DEFAULT 'system';
@@ -0,0 +1,2 @@
+-- users created before credentials existed have an empty hash and cannot log in until a password is set
+ALTER TABLE users ADD COLUMN password_hash VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- every existing user becomes a customer, admins are granted with the grant-role command
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer';
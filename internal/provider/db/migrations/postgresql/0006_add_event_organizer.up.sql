@@ -0,0 +1,2 @@
+-- events created before organizers existed have no owner and can only be managed by admins
+ALTER TABLE events ADD COLUMN organizer_id INTEGER REFERENCES users (user_id);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (user_id),
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL UNIQUE,
+	key_hash VARCHAR(64) NOT NULL,
+	scopes VARCHAR(255) NOT NULL,
+	expires_at TIMESTAMP NULL,
+	last_used_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP NULL,
+	created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+	updated_by VARCHAR(255) NOT NULL DEFAULT 'system'
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	usage_id SERIAL PRIMARY KEY,
+	key_id INTEGER NOT NULL REFERENCES api_keys (key_id),
+	method VARCHAR(10) NOT NULL,
+	path VARCHAR(2048) NOT NULL,
+	remote_addr VARCHAR(255) NOT NULL,
+	used_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_key_usage_key_id_idx ON api_key_usage (key_id, used_at);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS events (
+	event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tickets (
+	ticket_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id INTEGER NOT NULL REFERENCES events (event_id),
+	name TEXT NOT NULL,
+	price REAL NOT NULL,
+	stock INTEGER NOT NULL,
+	type TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL,
+	name TEXT NOT NULL,
+	phone_number TEXT NOT NULL,
+	balance REAL NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ticket_order (
+	order_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ticket_id INTEGER NOT NULL REFERENCES tickets (ticket_id),
+	user_id INTEGER NOT NULL REFERENCES users (user_id),
+	amount INTEGER NOT NULL,
+	total_price REAL NOT NULL
+);
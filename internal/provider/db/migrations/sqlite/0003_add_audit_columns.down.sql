@@ -0,0 +1,23 @@
+ALTER TABLE ticket_order DROP COLUMN updated_by;
+ALTER TABLE ticket_order DROP COLUMN created_by;
+ALTER TABLE ticket_order DROP COLUMN deleted_at;
+ALTER TABLE ticket_order DROP COLUMN updated_at;
+ALTER TABLE ticket_order DROP COLUMN created_at;
+
+ALTER TABLE users DROP COLUMN updated_by;
+ALTER TABLE users DROP COLUMN created_by;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
+
+ALTER TABLE tickets DROP COLUMN updated_by;
+ALTER TABLE tickets DROP COLUMN created_by;
+ALTER TABLE tickets DROP COLUMN deleted_at;
+ALTER TABLE tickets DROP COLUMN updated_at;
+ALTER TABLE tickets DROP COLUMN created_at;
+
+ALTER TABLE events DROP COLUMN updated_by;
+ALTER TABLE events DROP COLUMN created_by;
+ALTER TABLE events DROP COLUMN deleted_at;
+ALTER TABLE events DROP COLUMN updated_at;
+ALTER TABLE events DROP COLUMN created_at;
@@ -0,0 +1,28 @@
+-- sqlite cannot add a column with a non constant default, so existing rows are stamped afterwards
+ALTER TABLE events ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system';
+ALTER TABLE events ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+UPDATE events SET created_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP;
+
+ALTER TABLE tickets ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE tickets ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE tickets ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE tickets ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system';
+ALTER TABLE tickets ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+UPDATE tickets SET created_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP;
+
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system';
+ALTER TABLE users ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+UPDATE users SET created_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP;
+
+ALTER TABLE ticket_order ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE ticket_order ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE ticket_order ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE ticket_order ADD COLUMN created_by TEXT NOT NULL DEFAULT 'system';
+ALTER TABLE ticket_order ADD COLUMN updated_by TEXT NOT NULL DEFAULT 'system';
+UPDATE ticket_order SET created_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP;
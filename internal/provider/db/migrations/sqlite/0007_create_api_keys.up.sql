@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (user_id),
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL UNIQUE,
+	key_hash TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP,
+	created_by TEXT NOT NULL DEFAULT 'system',
+	updated_by TEXT NOT NULL DEFAULT 'system'
+);
+
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	usage_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key_id INTEGER NOT NULL REFERENCES api_keys (key_id),
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	remote_addr TEXT NOT NULL,
+	used_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_key_usage_key_id_idx ON api_key_usage (key_id, used_at);
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+)
+
+// Check reports why a dependency can't be used, or nil when it is fine
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status   string
+	Error    string
+	Duration time.Duration
+}
+
+// Report is the readiness of the service, up only when every check is
+type Report struct {
+	Status string
+	Checks map[string]Result
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks of the service's dependencies. Once draining it reports
+// not ready without running them, so load balancers stop sending traffic before the server shuts down.
+type Checker struct {
+	mtx      sync.RWMutex
+	checks   []namedCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// NewChecker gives every check up to timeout before counting it as down
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+	}
+}
+
+// Add registers check under name, shown in the report
+func (c *Checker) Add(name string, check Check) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain makes every following report not ready, for the rest of the process
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready runs the checks at the same time and reports each of them
+func (c *Checker) Ready(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: StatusDraining, Checks: map[string]Result{}}
+	}
+
+	c.mtx.RLock()
+	checks := c.checks
+	c.mtx.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+
+	for i, named := range checks {
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, named.check)
+	}
+
+	wg.Wait()
+
+	for i, named := range checks {
+		report.Checks[named.name] = results[i]
+
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := Result{Status: StatusUp, Duration: time.Since(start)}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,81 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type phase struct {
+	name string
+	stop func(ctx context.Context) error
+	// release phases free what the phases before them may still be using
+	release bool
+}
+
+// Lifecycle shuts the service down in phases, run one after the other in the order they were added,
+// so each can rely on the ones after it still being there: requests finish while the database is open.
+type Lifecycle struct {
+	mtx    sync.Mutex
+	phases []phase
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// OnShutdown adds a phase run after the ones added before it. stop gets the context of the whole shutdown,
+// a phase that can't finish before it is done should give up and return the error.
+func (l *Lifecycle) OnShutdown(name string, stop func(ctx context.Context) error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.phases = append(l.phases, phase{name: name, stop: stop})
+}
+
+// OnRelease adds a phase freeing something the phases before it use, such as the database. It is skipped
+// once an earlier phase failed or ran out of time, work it didn't wait for may still be using what it frees.
+func (l *Lifecycle) OnRelease(name string, release func(ctx context.Context) error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.phases = append(l.phases, phase{name: name, stop: release, release: true})
+}
+
+// Shutdown runs every phase within timeout. A failed or late phase doesn't stop the next ones, except
+// for the release phases, and all the errors are returned together.
+func (l *Lifecycle) Shutdown(timeout time.Duration) error {
+	l.mtx.Lock()
+	phases := l.phases
+	l.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	var errs []error
+
+	for _, phase := range phases {
+		if phase.release && len(errs) > 0 {
+			log.Warn().Msg(fmt.Sprintf("Shutdown phase %s skipped, %d earlier phases failed and may still use it", phase.name, len(errs)))
+			errs = append(errs, fmt.Errorf("%s: skipped after earlier phases failed", phase.name))
+			continue
+		}
+
+		phaseStart := time.Now()
+		log.Info().Msg(fmt.Sprintf("Shutdown phase %s started", phase.name))
+
+		if err := phase.stop(ctx); err != nil {
+			log.Error().Msg(fmt.Sprintf("Shutdown phase %s failed after %s: %s", phase.name, time.Since(phaseStart), err))
+			errs = append(errs, fmt.Errorf("%s: %w", phase.name, err))
+			continue
+		}
+
+		log.Info().Msg(fmt.Sprintf("Shutdown phase %s done in %s", phase.name, time.Since(phaseStart)))
+	}
+
+	log.Info().Msg(fmt.Sprintf("Shutdown finished in %s with %d failed phases", time.Since(start), len(errs)))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric served on /metrics, next to the Go runtime and process ones
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	repositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_operation_duration_seconds",
+		Help:    "Time taken by repository operations, including waiting for a connection.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"repository", "operation"})
+
+	ordersPlaced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ticket_orders_placed_total",
+		Help: "Ticket orders placed.",
+	})
+
+	ordersFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ticket_orders_failed_total",
+		Help: "Ticket orders that could not be placed, by reason.",
+	}, []string{"reason"})
+
+	ticketsSold = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "tickets_sold_total",
+		Help: "Tickets sold, by event.",
+	}, []string{"event_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		repositoryDuration,
+		ordersPlaced,
+		ordersFailed,
+		ticketsSold,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exposes the connection pool stats of database, named after the database
+func RegisterDB(database *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(database, name))
+}
+
+// RegisterGauge exposes a value read on every scrape, such as the length of a queue
+func RegisterGauge(name string, help string, value func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, value))
+}
+
+// RegisterCounter exposes a count kept elsewhere, read on every scrape
+func RegisterCounter(name string, help string, value func() float64) {
+	Registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, value))
+}
+
+// ObserveRequest counts a served request. Requests matching no route share the route "unmatched",
+// so clients probing random paths can't grow the number of series.
+func ObserveRequest(method string, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveRepository starts timing a repository operation, the returned func stops it:
+//
+//	defer metrics.ObserveRepository("event", "save")()
+func ObserveRepository(repository string, operation string) func() {
+	start := time.Now()
+
+	return func() {
+		repositoryDuration.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// OrderPlaced counts an order of amount tickets for the event
+func OrderPlaced(eventID int, amount int) {
+	ordersPlaced.Inc()
+	ticketsSold.WithLabelValues(strconv.Itoa(eventID)).Add(float64(amount))
+}
+
+// OrderFailed counts an order that was refused or failed, reason being a short kind like "insufficient_stock"
+func OrderFailed(reason string) {
+	ordersFailed.WithLabelValues(reason).Inc()
+}
@@ -0,0 +1,214 @@
+package waitingroom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrUnknownToken = errors.New("unknown or expired queue token")
+
+// Config sets how fast the room lets clients through
+type Config struct {
+	// AdmitBatch clients are admitted every AdmitEvery
+	AdmitBatch int
+	AdmitEvery time.Duration
+	// AdmitTTL is how long an admitted client may keep buying before having to queue again
+	AdmitTTL time.Duration
+	// IdleTimeout drops queued clients that stopped checking their position, so they don't use up admissions
+	IdleTimeout time.Duration
+}
+
+// Position is where a token stands, Place is 0 once it is admitted
+type Position struct {
+	Token         string
+	Place         int
+	Admitted      bool
+	AdmittedUntil time.Time
+	EstimatedWait time.Duration
+}
+
+type entry struct {
+	token         string
+	userID        int
+	seq           int
+	lastSeen      time.Time
+	admittedUntil time.Time
+}
+
+// WaitingRoom queues clients in the order they joined and admits them in batches, one token per user
+type WaitingRoom struct {
+	mtx      sync.Mutex
+	config   Config
+	queue    []*entry
+	admitted map[string]*entry
+	tokens   map[string]*entry
+	byUser   map[int]*entry
+	// nextSeq is given to the next client joining, headSeq is the one of the next client to admit
+	nextSeq int
+	headSeq int
+}
+
+func NewWaitingRoom(config Config) *WaitingRoom {
+	if config.AdmitBatch < 1 {
+		config.AdmitBatch = 1
+	}
+
+	return &WaitingRoom{
+		config:   config,
+		admitted: map[string]*entry{},
+		tokens:   map[string]*entry{},
+		byUser:   map[int]*entry{},
+	}
+}
+
+// Join queues userID, or returns where the user already stands when they joined before
+func (room *WaitingRoom) Join(userID int) (Position, error) {
+	room.mtx.Lock()
+	defer room.mtx.Unlock()
+
+	now := time.Now()
+
+	if found, ok := room.byUser[userID]; ok && room.live(found, now) {
+		found.lastSeen = now
+		return room.position(found), nil
+	}
+
+	token, err := newToken()
+
+	if err != nil {
+		return Position{}, err
+	}
+
+	joined := &entry{token: token, userID: userID, seq: room.nextSeq, lastSeen: now}
+	room.nextSeq++
+	room.queue = append(room.queue, joined)
+	room.tokens[token] = joined
+	room.byUser[userID] = joined
+
+	log.Debug().Msg(fmt.Sprintf("User with ID %d joined the waiting room at place %d", userID, len(room.queue)))
+	return room.position(joined), nil
+}
+
+// Position reports where token stands, only to the user it was given to
+func (room *WaitingRoom) Position(token string, userID int) (Position, error) {
+	room.mtx.Lock()
+	defer room.mtx.Unlock()
+
+	now := time.Now()
+	found, ok := room.tokens[token]
+
+	if !ok || found.userID != userID || !room.live(found, now) {
+		return Position{}, ErrUnknownToken
+	}
+
+	found.lastSeen = now
+	return room.position(found), nil
+}
+
+// Admitted reports whether token was admitted for userID and its admission has not run out
+func (room *WaitingRoom) Admitted(token string, userID int) bool {
+	room.mtx.Lock()
+	defer room.mtx.Unlock()
+
+	found, ok := room.admitted[token]
+	return ok && found.userID == userID && time.Now().Before(found.admittedUntil)
+}
+
+// Run admits a batch every AdmitEvery until ctx is done
+func (room *WaitingRoom) Run(ctx context.Context) {
+	ticker := time.NewTicker(room.config.AdmitEvery)
+	defer ticker.Stop()
+
+	log.Info().Msg(fmt.Sprintf("Waiting room admitting %d clients every %s", room.config.AdmitBatch, room.config.AdmitEvery))
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Waiting room stopped admitting")
+			return
+		case now := <-ticker.C:
+			room.admit(now)
+		}
+	}
+}
+
+func (room *WaitingRoom) admit(now time.Time) {
+	room.mtx.Lock()
+	defer room.mtx.Unlock()
+
+	for token, found := range room.admitted {
+		if !now.Before(found.admittedUntil) {
+			room.forget(found)
+			delete(room.admitted, token)
+		}
+	}
+
+	admitted := 0
+
+	for admitted < room.config.AdmitBatch && len(room.queue) > 0 {
+		next := room.queue[0]
+		room.queue[0] = nil
+		room.queue = room.queue[1:]
+		room.headSeq = next.seq + 1
+
+		if now.Sub(next.lastSeen) > room.config.IdleTimeout {
+			log.Debug().Msg(fmt.Sprintf("User with ID %d left the waiting room, skipping", next.userID))
+			room.forget(next)
+			continue
+		}
+
+		next.admittedUntil = now.Add(room.config.AdmitTTL)
+		room.admitted[next.token] = next
+		admitted++
+	}
+
+	if admitted > 0 {
+		log.Info().Msg(fmt.Sprintf("Waiting room admitted %d clients, %d still queued", admitted, len(room.queue)))
+	}
+}
+
+// live reports whether a token is still queued or admitted, expired ones wait for admit to clean them up
+func (room *WaitingRoom) live(found *entry, now time.Time) bool {
+	return found.admittedUntil.IsZero() || now.Before(found.admittedUntil)
+}
+
+func (room *WaitingRoom) forget(found *entry) {
+	delete(room.tokens, found.token)
+
+	if room.byUser[found.userID] == found {
+		delete(room.byUser, found.userID)
+	}
+}
+
+func (room *WaitingRoom) position(found *entry) Position {
+	if !found.admittedUntil.IsZero() {
+		return Position{Token: found.token, Admitted: true, AdmittedUntil: found.admittedUntil}
+	}
+
+	// clients ahead that left are still counted until admit skips them, so the place only ever goes down
+	place := found.seq - room.headSeq + 1
+	batches := (place + room.config.AdmitBatch - 1) / room.config.AdmitBatch
+
+	return Position{
+		Token:         found.token,
+		Place:         place,
+		EstimatedWait: time.Duration(batches) * room.config.AdmitEvery,
+	}
+}
+
+func newToken() (string, error) {
+	token := make([]byte, 16)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("generating queue token failed: %w", err)
+	}
+
+	return hex.EncodeToString(token), nil
+}
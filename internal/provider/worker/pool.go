@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"ticket_goroutine/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrQueueFull = errors.New("work queue is full")
+	ErrStopped   = errors.New("work queue is no longer accepting jobs")
+)
+
+type Job func()
+
+// Pool runs jobs on a fixed number of workers, queueing at most queueSize of them.
+// Submitting never blocks, a full queue is reported instead so callers can push back on clients.
+type Pool struct {
+	name    string
+	jobs    chan Job
+	wg      sync.WaitGroup
+	mtx     sync.RWMutex
+	stopped bool
+}
+
+// NewPool starts workers goroutines that run the submitted jobs until the pool is drained
+func NewPool(name string, workers int, queueSize int) *Pool {
+	pool := &Pool{
+		name: name,
+		jobs: make(chan Job, queueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go pool.work()
+	}
+
+	log.Info().Msg(fmt.Sprintf("Worker pool for %s started with %d workers and room for %d queued jobs", name, workers, queueSize))
+	return pool
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for job := range p.jobs {
+		p.run(job)
+	}
+}
+
+// run keeps the worker alive when a job panics
+func (p *Pool) run(job Job) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err := utils.Recovered(recovered)
+			log.Error().Str("stack", string(err.Stack)).Msg(fmt.Sprintf("Job in the worker pool for %s panicked: %s", p.name, err))
+		}
+	}()
+
+	job()
+}
+
+// Submit queues job, returning ErrQueueFull when the queue is full and ErrStopped once the pool is draining
+func (p *Pool) Submit(job Job) error {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	default:
+		log.Warn().Msg(fmt.Sprintf("Worker pool for %s is full with %d queued jobs", p.name, len(p.jobs)))
+		return ErrQueueFull
+	}
+}
+
+// Queued is the number of jobs waiting for a worker
+func (p *Pool) Queued() int {
+	return len(p.jobs)
+}
+
+// Running reports whether the pool still accepts jobs, it stops for good once drained
+func (p *Pool) Running() bool {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return !p.stopped
+}
+
+// Drain stops accepting jobs and waits for the queued and running ones to finish, or for ctx to be done
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mtx.Lock()
+
+	if !p.stopped {
+		p.stopped = true
+		close(p.jobs)
+	}
+
+	p.mtx.Unlock()
+
+	log.Info().Msg(fmt.Sprintf("Draining worker pool for %s with %d queued jobs", p.name, len(p.jobs)))
+	done := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("worker pool for %s did not drain in time: %w", p.name, ctx.Err())
+	case <-done:
+		log.Info().Msg(fmt.Sprintf("Worker pool for %s drained", p.name))
+		return nil
+	}
+}
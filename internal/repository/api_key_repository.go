@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"ticket_goroutine/internal/domain"
+)
+
+type APIKeyRepositoryInterface interface {
+	APIKeySave
+	APIKeyFindById
+	APIKeyFindByPrefix
+	APIKeyGetAll
+	APIKeyDelete
+	APIKeyRecordUsage
+	APIKeyGetUsage
+}
+
+type APIKeySave interface {
+	Save(context context.Context, key *domain.APIKey) (error)
+}
+
+type APIKeyFindById interface {
+	FindByID(context context.Context, id int) (domain.APIKey, error)
+}
+
+type APIKeyFindByPrefix interface {
+	FindByPrefix(context context.Context, prefix string) (domain.APIKey, error)
+}
+
+type APIKeyGetAll interface {
+	GetAll(context context.Context) ([]domain.APIKey, error)
+}
+
+type APIKeyDelete interface {
+	Delete(context context.Context, id int) (error)
+}
+
+type APIKeyRecordUsage interface {
+	RecordUsage(context context.Context, usage *domain.APIKeyUsage) (error)
+}
+
+type APIKeyGetUsage interface {
+	GetUsage(context context.Context, keyID int) ([]domain.APIKeyUsage, error)
+}
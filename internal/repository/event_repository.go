@@ -9,6 +9,9 @@ type EventRepositoryInterface interface {
 	EventSave
 	EventFindById
 	EventGetAll
+	EventGetByOrganizerId
+	EventUpdate
+	EventDelete
 }
 
 type EventSave interface {
@@ -21,4 +24,16 @@ type EventFindById interface {
 
 type EventGetAll interface {
 	GetAll(context context.Context) ([]domain.Event, error)
+}
+
+type EventGetByOrganizerId interface {
+	GetByOrganizerID(context context.Context, organizerID int) ([]domain.Event, error)
+}
+
+type EventUpdate interface {
+	Update(context context.Context, event *domain.Event) (error)
+}
+
+type EventDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
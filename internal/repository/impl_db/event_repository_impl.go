@@ -7,10 +7,13 @@ import (
 	"sync"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/repository"
+	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
+const eventColumns = `event_id, event_name, organizer_id, version, ` + auditColumns
+
 type EventRepository struct {
 	mtx sync.Mutex
 	db *sql.DB
@@ -22,31 +25,64 @@ func NewEventRepository(database *sql.DB) repository.EventRepositoryInterface {
 	}
 }
 
+func scanEvent(row rowScanner) (domain.Event, error) {
+	var event domain.Event
+	var organizerID sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&event.EventID,
+		&event.EventName,
+		&organizerID,
+		&event.Version,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+		&deletedAt,
+		&event.CreatedBy,
+		&event.UpdatedBy,
+	)
+	event.OrganizerID = nullInt(organizerID)
+	event.DeletedAt = nullTime(deletedAt)
+
+	return event, err
+}
+
 func (repo *EventRepository) Save(ctx context.Context, event *domain.Event) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "save")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside event repository save")
+	logger.Trace().Msg("Inside event repository save")
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to save event because of timeout with message: %s", ctx.Err()))
-		return ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to save new event")
+		logger.Trace().Msg("Attempting to save new event")
 
 		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		logger.Trace().Msg("Begin transaction")
 
 		if err != nil {
 			return err
 		}
 
-		query := `INSERT INTO events (event_name) VALUES ($1) RETURNING event_id`
-		log.Trace().Msg("Query is set")
+		defer trx.Rollback()
+
+		query := `
+			INSERT INTO
+				events
+				(event_name, organizer_id, created_at, updated_at, created_by, updated_by)
+			VALUES
+				($1, $2, $3, $3, $4, $4)
+			RETURNING ` + eventColumns
+		logger.Trace().Msg("Query is set")
 
 		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return err
@@ -54,49 +90,45 @@ func (repo *EventRepository) Save(ctx context.Context, event *domain.Event) (err
 
 		defer stmt.Close()
 
-		errScan := stmt.QueryRowContext(ctx, event.EventName).Scan(&event.EventID)
-		log.Trace().Msg("Query ran")
+		savedEvent, errScan := scanEvent(stmt.QueryRowContext(ctx, event.EventName, nullID(event.OrganizerID), time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
 
 		if errScan != nil {
-			trx.Rollback()
 			return errScan
 		}
 
 		if err = trx.Commit(); err != nil {
 			return err
 		}
-		
-		log.Info().Msg("New event saved")
+
+		*event = savedEvent
+		logger.Info().Msg("New event saved")
 		return nil
 	}
 }
 
 func (repo *EventRepository) FindByID(ctx context.Context, id int) (domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "find_by_id")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside event repository find by id")
-	log.Debug().Msg(fmt.Sprintf("Event repo find by id received id with value %d", id))
-	
+	logger.Trace().Msg("Inside event repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Event repo find by id received id with value %d", id))
+
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
-		return domain.Event{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
+		return domain.Event{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch event")
-	
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin Transaction")
+		logger.Trace().Msg("Attempting to fetch event")
 
-		if err != nil {
-			return domain.Event{}, err
-		}
+		query := `SELECT ` + eventColumns + ` FROM events WHERE event_id=$1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
 
-		query := "SELECT event_id, event_name FROM events WHERE event_id=$1"
-		log.Trace().Msg("Query is set")
-
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return domain.Event{}, err
@@ -104,55 +136,52 @@ func (repo *EventRepository) FindByID(ctx context.Context, id int) (domain.Event
 
 		defer stmt.Close()
 
-		var event domain.Event
-		errScan := stmt.QueryRowContext(ctx, id).Scan(
-			&event.EventID,
-			&event.EventName,
-		)
-		log.Trace().Msg("Query ran")
+		event, errScan := scanEvent(stmt.QueryRowContext(ctx, id))
+		logger.Trace().Msg("Query ran")
 
 		if errScan != nil {
 			if errScan == sql.ErrNoRows {
-				log.Error().Msg(fmt.Sprintf("Event with ID %d not found", id))
-				return domain.Event{}, fmt.Errorf("no Event found with ID %d", id)
+				logger.Error().Msg(fmt.Sprintf("Event with ID %d not found", id))
+				return domain.Event{}, &domain.NotFoundError{Entity: "Event", ID: id}
 			}
 			return domain.Event{}, errScan
 		}
 
-		if err = trx.Commit(); err != nil {
-			return domain.Event{}, err
-		}
-
-		log.Info().Msg("Event repo find by id completed")
+		logger.Info().Msg("Event repo find by id completed")
 		return event, nil
 	}
 }
 
 func (repo *EventRepository) GetAll(ctx context.Context) ([]domain.Event, error) {
+	ctx, end := observe(ctx, "event", "get_all")
+	defer end()
+	zerolog.Ctx(ctx).Trace().Msg("Inside event repository get all")
+	return repo.list(ctx, `SELECT `+eventColumns+` FROM events WHERE `+notDeleted(ctx, "deleted_at")+` ORDER BY event_id`)
+}
+
+func (repo *EventRepository) GetByOrganizerID(ctx context.Context, organizerID int) ([]domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "get_by_organizer_id")
+	defer end()
+	logger.Trace().Msg("Inside event repository get by organizer id")
+	logger.Debug().Msg(fmt.Sprintf("Event repo get by organizer id received id with value %d", organizerID))
+	return repo.list(ctx, `SELECT `+eventColumns+` FROM events WHERE organizer_id=$1 AND `+notDeleted(ctx, "deleted_at")+` ORDER BY event_id`, organizerID)
+}
+
+func (repo *EventRepository) list(ctx context.Context, query string, args ...any) ([]domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside event repository get all")
-
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
-		return []domain.Event{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
+		return []domain.Event{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch event")
-		
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
-
-		if err != nil {
-			return []domain.Event{}, err
-		}
+		logger.Trace().Msg("Attempting to fetch event")
 
-		query := "SELECT * FROM events"
-		log.Trace().Msg("Query is set")
-
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return []domain.Event{}, err
@@ -160,8 +189,8 @@ func (repo *EventRepository) GetAll(ctx context.Context) ([]domain.Event, error)
 
 		defer stmt.Close()
 
-		res, err := stmt.QueryContext(ctx)
-		log.Trace().Msg("Query ran")
+		res, err := stmt.QueryContext(ctx, args...)
+		logger.Trace().Msg("Query ran")
 
 		if err != nil {
 			return []domain.Event{}, err
@@ -172,13 +201,146 @@ func (repo *EventRepository) GetAll(ctx context.Context) ([]domain.Event, error)
 		var listOfEvents []domain.Event
 
 		for res.Next() {
-			var event domain.Event
-			res.Scan(&event.EventID, &event.EventName)
+			event, err := scanEvent(res)
+
+			if err != nil {
+				return []domain.Event{}, err
+			}
 
 			listOfEvents = append(listOfEvents, event)
 		}
 
-		log.Info().Msg("Fetching completed")
+		if err := res.Err(); err != nil {
+			return []domain.Event{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
 		return listOfEvents, nil
 	}
-}
\ No newline at end of file
+}
+
+func (repo *EventRepository) Update(ctx context.Context, event *domain.Event) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository update")
+	logger.Debug().Msg(fmt.Sprintf("Event repo update received id with value %d and version %d", event.EventID, event.Version))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update event")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			UPDATE
+				events
+			SET
+				event_name=$1,
+				organizer_id=$2,
+				version=version+1,
+				updated_at=$3,
+				updated_by=$4
+			WHERE
+				event_id=$5
+				AND version=$6
+				AND deleted_at IS NULL
+			RETURNING ` + eventColumns
+		logger.Trace().Msg("Query is set")
+
+		stmt, err := trx.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
+
+		if err != nil {
+			return err
+		}
+
+		defer stmt.Close()
+
+		updatedEvent, errScan := scanEvent(stmt.QueryRowContext(ctx, event.EventName, nullID(event.OrganizerID), time.Now().UTC(), domain.ActorFromContext(ctx), event.EventID, event.Version))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				return missingOrConflict(ctx, trx, "event", "SELECT COUNT(*) FROM events WHERE event_id=$1 AND deleted_at IS NULL", event.EventID, event.Version)
+			}
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*event = updatedEvent
+		logger.Info().Msg("Event updated")
+		return nil
+	}
+}
+
+func (repo *EventRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Event repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete event and its tickets")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		now := time.Now().UTC()
+		actor := domain.ActorFromContext(ctx)
+
+		res, err := trx.ExecContext(ctx, `UPDATE events SET deleted_at=$1, updated_at=$1, updated_by=$2, version=version+1 WHERE event_id=$3 AND deleted_at IS NULL`, now, actor, id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Event with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Event", ID: id}
+		}
+
+		if _, err := trx.ExecContext(ctx, `UPDATE tickets SET deleted_at=$1, updated_at=$1, updated_by=$2, version=version+1 WHERE event_id=$3 AND deleted_at IS NULL`, now, actor, id); err != nil {
+			return err
+		}
+		logger.Trace().Msg("Tickets of the event deleted")
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		logger.Info().Msg("Event deleted")
+		return nil
+	}
+}
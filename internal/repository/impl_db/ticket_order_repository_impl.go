@@ -7,10 +7,13 @@ import (
 	"sync"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/repository"
+	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
+const ticketOrderColumns = `order_id, ticket_id, user_id, amount, total_price, ` + auditColumns
+
 type TicketOrderRepository struct {
 	mtx sync.Mutex
 	db *sql.DB
@@ -22,38 +25,63 @@ func NewTicketOrderRepository(database *sql.DB) repository.TicketOrderRepository
 	}
 }
 
+func scanTicketOrder(row rowScanner) (domain.TicketOrder, error) {
+	var ticketOrder domain.TicketOrder
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&ticketOrder.OrderID,
+		&ticketOrder.TicketID,
+		&ticketOrder.UserID,
+		&ticketOrder.Amount,
+		&ticketOrder.TotalPrice,
+		&ticketOrder.CreatedAt,
+		&ticketOrder.UpdatedAt,
+		&deletedAt,
+		&ticketOrder.CreatedBy,
+		&ticketOrder.UpdatedBy,
+	)
+	ticketOrder.DeletedAt = nullTime(deletedAt)
+
+	return ticketOrder, err
+}
+
 func (repo *TicketOrderRepository) Save(ctx context.Context, ticketOrder *domain.TicketOrder) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "save")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket order repository save")
+	logger.Trace().Msg("Inside ticket order repository save")
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to save ticket order because of timeout with message: %s", ctx.Err()))
-		return ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to save new ticket order")
-		
+		logger.Trace().Msg("Attempting to save new ticket order")
+
 		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		logger.Trace().Msg("Begin transaction")
 
 		if err != nil {
 			return err
 		}
 
+		defer trx.Rollback()
+
 		query := `
 			INSERT INTO
 				ticket_order
-				(ticket_id, user_id, amount, total_price)
+				(ticket_id, user_id, amount, total_price, created_at, updated_at, created_by, updated_by)
 			VALUES
-			($1, $2, $3, $4)
-			RETURNING order_id
-		`
-		log.Trace().Msg("Query is set")
+				($1, $2, $3, $4, $5, $5, $6, $6)
+			RETURNING ` + ticketOrderColumns
+		logger.Trace().Msg("Query is set")
 
 		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return err
@@ -61,11 +89,10 @@ func (repo *TicketOrderRepository) Save(ctx context.Context, ticketOrder *domain
 
 		defer stmt.Close()
 
-		errScan := stmt.QueryRowContext(ctx, ticketOrder.TicketID, ticketOrder.UserID, ticketOrder.Amount, ticketOrder.TotalPrice).Scan(&ticketOrder.OrderID)
-		log.Trace().Msg("Query ran")
+		savedTicketOrder, errScan := scanTicketOrder(stmt.QueryRowContext(ctx, ticketOrder.TicketID, ticketOrder.UserID, ticketOrder.Amount, ticketOrder.TotalPrice, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
 
 		if errScan != nil {
-			trx.Rollback()
 			return errScan
 		}
 
@@ -73,46 +100,34 @@ func (repo *TicketOrderRepository) Save(ctx context.Context, ticketOrder *domain
 			return err
 		}
 
-		log.Info().Msg("New ticket order saved")
+		*ticketOrder = savedTicketOrder
+		logger.Info().Msg("New ticket order saved")
 		return nil
 	}
 }
 
 func (repo *TicketOrderRepository) FindByID(ctx context.Context, id int) (domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "find_by_id")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket order repository find by id")
-	log.Debug().Msg(fmt.Sprintf("Ticket order repo find by id received id value is %d", id))
+	logger.Trace().Msg("Inside ticket order repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo find by id received id value is %d", id))
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
-		return domain.TicketOrder{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.TicketOrder{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch ticket order")
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		logger.Trace().Msg("Attempting to fetch ticket order")
 
-		if err != nil {
-			return domain.TicketOrder{}, err
-		}
-
-		query := `
-			SELECT
-				t.order_id,
-				t.ticket_id,
-				t.user_id,
-				t.amount,
-				t.total_price
-			FROM ticket_order t
-			WHERE
-				t.order_id=$1
-		`
-		log.Trace().Msg("Query is set")
+		query := `SELECT ` + ticketOrderColumns + ` FROM ticket_order WHERE order_id=$1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
 
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return domain.TicketOrder{}, err
@@ -120,57 +135,66 @@ func (repo *TicketOrderRepository) FindByID(ctx context.Context, id int) (domain
 
 		defer stmt.Close()
 
-		var ticketOrder domain.TicketOrder
-
-		errScan := stmt.QueryRowContext(ctx, id).Scan(
-			&ticketOrder.OrderID,
-			&ticketOrder.TicketID,
-			&ticketOrder.UserID,
-			&ticketOrder.Amount,
-			&ticketOrder.TotalPrice,
-		)
+		ticketOrder, errScan := scanTicketOrder(stmt.QueryRowContext(ctx, id))
 
 		if errScan != nil {
 			if errScan == sql.ErrNoRows {
-				log.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", id))
-				return domain.TicketOrder{}, fmt.Errorf("no Ticket order found with ID %d", id)
+				logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", id))
+				return domain.TicketOrder{}, &domain.NotFoundError{Entity: "Ticket order", ID: id}
 			}
-			return domain.TicketOrder{}, err
-		}
-
-		if err = trx.Commit(); err != nil {
-			return domain.TicketOrder{}, err
+			return domain.TicketOrder{}, errScan
 		}
 
-		log.Info().Msg("Ticket order repo find by id completed")
+		logger.Info().Msg("Ticket order repo find by id completed")
 		return ticketOrder, nil
 	}
 }
 
 func (repo *TicketOrderRepository) GetAll(ctx context.Context) ([]domain.TicketOrder, error) {
+	ctx, end := observe(ctx, "ticket_order", "get_all")
+	defer end()
+	zerolog.Ctx(ctx).Trace().Msg("Inside ticket order repository get all")
+	return repo.list(ctx, `SELECT `+ticketOrderColumns+` FROM ticket_order WHERE `+notDeleted(ctx, "deleted_at")+` ORDER BY order_id`)
+}
+
+// GetByOrganizerID lists the orders placed for tickets of the organizer's events
+func (repo *TicketOrderRepository) GetByOrganizerID(ctx context.Context, organizerID int) ([]domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "get_by_organizer_id")
+	defer end()
+	logger.Trace().Msg("Inside ticket order repository get by organizer id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo get by organizer id received id with value %d", organizerID))
+
+	query := `
+		SELECT ` + qualified("o", ticketOrderColumns) + `
+		FROM ticket_order o
+		JOIN tickets t
+		ON o.ticket_id=t.ticket_id
+		JOIN events e
+		ON t.event_id=e.event_id
+		WHERE
+			e.organizer_id=$1
+			AND ` + notDeleted(ctx, "o.deleted_at") + `
+		ORDER BY o.order_id
+	`
+
+	return repo.list(ctx, query, organizerID)
+}
+
+func (repo *TicketOrderRepository) list(ctx context.Context, query string, args ...any) ([]domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket order repository get all")
-
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
-		return []domain.TicketOrder{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
+		return []domain.TicketOrder{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch ticket order")
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		logger.Trace().Msg("Attempting to fetch ticket order")
 
-		if err != nil {
-			return []domain.TicketOrder{}, err
-		}
-
-		query := `SELECT * FROM ticket_order`
-		log.Trace().Msg("Query is set")
-
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return []domain.TicketOrder{}, err
@@ -178,8 +202,8 @@ func (repo *TicketOrderRepository) GetAll(ctx context.Context) ([]domain.TicketO
 
 		defer stmt.Close()
 
-		res, err := stmt.QueryContext(ctx)
-		log.Trace().Msg("Query ran")
+		res, err := stmt.QueryContext(ctx, args...)
+		logger.Trace().Msg("Query ran")
 
 		if err != nil {
 			return []domain.TicketOrder{}, err
@@ -190,14 +214,154 @@ func (repo *TicketOrderRepository) GetAll(ctx context.Context) ([]domain.TicketO
 		var listOfTicketOrders []domain.TicketOrder
 
 		for res.Next() {
-			var ticketOrder domain.TicketOrder
+			ticketOrder, err := scanTicketOrder(res)
 
-			res.Scan(&ticketOrder.TicketID, &ticketOrder.OrderID, &ticketOrder.UserID, &ticketOrder.Amount, &ticketOrder.TotalPrice)
+			if err != nil {
+				return []domain.TicketOrder{}, err
+			}
 
 			listOfTicketOrders = append(listOfTicketOrders, ticketOrder)
 		}
-		
-		log.Info().Msg("Fetching completed")
+
+		if err := res.Err(); err != nil {
+			return []domain.TicketOrder{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
 		return listOfTicketOrders, nil
 	}
-}
\ No newline at end of file
+}
+
+func (repo *TicketOrderRepository) Update(ctx context.Context, ticketOrder *domain.TicketOrder) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository update")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo update received id value is %d", ticketOrder.OrderID))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update ticket order")
+
+		query := `
+			UPDATE
+				ticket_order
+			SET
+				amount=$1,
+				total_price=$2,
+				updated_at=$3,
+				updated_by=$4
+			WHERE
+				order_id=$5
+				AND deleted_at IS NULL
+			RETURNING ` + ticketOrderColumns
+		logger.Trace().Msg("Query is set")
+
+		updatedTicketOrder, errScan := scanTicketOrder(repo.db.QueryRowContext(ctx, query, ticketOrder.Amount, ticketOrder.TotalPrice, time.Now().UTC(), domain.ActorFromContext(ctx), ticketOrder.OrderID))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", ticketOrder.OrderID))
+				return &domain.NotFoundError{Entity: "Ticket order", ID: ticketOrder.OrderID}
+			}
+			return errScan
+		}
+
+		*ticketOrder = updatedTicketOrder
+		logger.Info().Msg("Ticket order updated")
+		return nil
+	}
+}
+
+func (repo *TicketOrderRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo delete received id value is %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete ticket order")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE ticket_order SET deleted_at=$1, updated_at=$1, updated_by=$2 WHERE order_id=$3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Ticket order", ID: id}
+		}
+
+		logger.Info().Msg("Ticket order deleted")
+		return nil
+	}
+}
+
+func (repo *TicketOrderRepository) CountByEventID(ctx context.Context, eventID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_event_id")
+	defer end()
+	query := `
+		SELECT
+			COUNT(*)
+		FROM ticket_order o
+		JOIN tickets t
+		ON o.ticket_id=t.ticket_id
+		WHERE
+			t.event_id=$1
+			AND o.deleted_at IS NULL
+	`
+
+	return repo.count(ctx, query, eventID)
+}
+
+func (repo *TicketOrderRepository) CountByTicketID(ctx context.Context, ticketID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_ticket_id")
+	defer end()
+	return repo.count(ctx, `SELECT COUNT(*) FROM ticket_order WHERE ticket_id=$1 AND deleted_at IS NULL`, ticketID)
+}
+
+func (repo *TicketOrderRepository) CountByUserID(ctx context.Context, userID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_user_id")
+	defer end()
+	return repo.count(ctx, `SELECT COUNT(*) FROM ticket_order WHERE user_id=$1 AND deleted_at IS NULL`, userID)
+}
+
+func (repo *TicketOrderRepository) count(ctx context.Context, query string, id int) (int, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository count")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to count ticket order because of timeout with message: %s", ctx.Err()))
+		return 0, domain.Timeout(ctx.Err())
+	default:
+		var count int
+
+		if err := repo.db.QueryRowContext(ctx, query, id).Scan(&count); err != nil {
+			return 0, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Ticket order count is %d", count))
+		return count, nil
+	}
+}
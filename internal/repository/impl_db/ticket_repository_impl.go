@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"sync"
 	"ticket_goroutine/internal/domain"
-	"ticket_goroutine/internal/domain/dto"
 	"ticket_goroutine/internal/repository"
+	"time"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
+const ticketColumns = `ticket_id, event_id, name, price, stock, type, version, ` + auditColumns
+
 type TicketRepository struct {
 	mtx sync.Mutex
 	db *sql.DB
@@ -23,98 +25,89 @@ func NewTicketRepository(database *sql.DB) repository.TicketRepositoryInterface
 	}
 }
 
+func scanTicket(row rowScanner) (domain.Ticket, error) {
+	var ticket domain.Ticket
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&ticket.TicketID,
+		&ticket.EventID,
+		&ticket.Name,
+		&ticket.Price,
+		&ticket.Stock,
+		&ticket.Type,
+		&ticket.Version,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+		&deletedAt,
+		&ticket.CreatedBy,
+		&ticket.UpdatedBy,
+	)
+	ticket.DeletedAt = nullTime(deletedAt)
+
+	return ticket, err
+}
+
 func (repo *TicketRepository) Save(ctx context.Context, ticket *domain.Ticket) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "save")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket repository save")
+	logger.Trace().Msg("Inside ticket repository save")
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to save ticket because of timeout with message: %s", ctx.Err()))
-		return ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to save new ticket")
+		logger.Trace().Msg("Attempting to save new ticket")
 		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		logger.Trace().Msg("Begin transaction")
 
 		if err != nil {
 			return err
 		}
 
-		log.Trace().Msg("Checking if ticket with the same Event ID and Type already exist")
-
-		query := `
-			SELECT
-				e.event_id,
-				e.event_name,
-				t.ticket_id,
-				t.name,
-				t.price,
-				t.type
-			FROM tickets t
-			JOIN events e
-			ON t.event_id=e.event_id
-			WHERE t.event_id=$1`
-
-		log.Trace().Msg("Query is set")
-		
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		defer trx.Rollback()
 
-		if err != nil {
-			return err
-		}
+		logger.Trace().Msg("Checking if ticket with the same Event ID and Type already exist")
 
-		defer stmt.Close()
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tickets WHERE event_id=$1 AND type=$2 AND deleted_at IS NULL`, ticket.EventID, ticket.Type).Scan(&count)
 
-		res, err := stmt.QueryContext(ctx, ticket.EventID)
-		log.Trace().Msg("Query ran")
-
-		if err != nil {
-			return err
+		if errCount != nil {
+			return errCount
 		}
 
-		defer res.Close()
-
-		var foundTicket dto.TicketResponseTicketOrder
-		for res.Next() {
-			log.Trace().Msg("Inside checking event and type")
-			log.Debug().Msg(fmt.Sprintf("%+v", res.Next()))
-
-			res.Scan(&foundTicket.EventDetails.EventID, &foundTicket.EventDetails.EventName, &foundTicket.TicketID, &foundTicket.Name, &foundTicket.Price, &foundTicket.Type)
-			log.Debug().
-				Int("Ticket ID: ", foundTicket.TicketID).
-				Int("Event ID: ", foundTicket.EventDetails.EventID).
-				Str("Event Name: ", foundTicket.EventDetails.EventName).
-				Str("Name: ", foundTicket.Name).
-				Float64("Price: ", foundTicket.Price).
-				Str("Type: ", foundTicket.Type).
-				Msg("")
-
-			if foundTicket.EventDetails.EventID == ticket.EventID && foundTicket.Type == ticket.Type {
-				log.Error().Msg(fmt.Sprintf("ticket for Event ID %d with Type %s already exist", ticket.EventID, ticket.Type))
-				return fmt.Errorf("ticket with Event ID %d with Type %s already exist", ticket.EventID, ticket.Type)
-			}
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("ticket for Event ID %d with Type %s already exist", ticket.EventID, ticket.Type))
+			return fmt.Errorf("ticket with Event ID %d with Type %s %w", ticket.EventID, ticket.Type, domain.ErrAlreadyExists)
 		}
 
-		query2 := `INSERT INTO tickets (event_id, name, price, stock, type) VALUES ($1, $2, $3, $4, $5) RETURNING ticket_id`
-		log.Trace().Msg("Query 2 is set")
+		query := `
+			INSERT INTO
+				tickets
+				(event_id, name, price, stock, type, created_at, updated_at, created_by, updated_by)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $6, $7, $7)
+			RETURNING ` + ticketColumns
+		logger.Trace().Msg("Query is set")
 
-		stmt2, err := trx.PrepareContext(ctx, query2)
-		log.Trace().Msg("Prepared statement 2 created with context")
+		stmt, err := trx.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return err
 		}
 
-		defer stmt2.Close()
+		defer stmt.Close()
 
-		errScan := stmt2.QueryRowContext(ctx, ticket.EventID, ticket.Name, ticket.Price, ticket.Stock, ticket.Type).Scan(&ticket.TicketID)
-		log.Trace().Msg("Query 2 ran")
+		savedTicket, errScan := scanTicket(stmt.QueryRowContext(ctx, ticket.EventID, ticket.Name, ticket.Price, ticket.Stock, ticket.Type, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
 
 		if errScan != nil {
-			trx.Rollback()
 			return errScan
 		}
 
@@ -122,48 +115,34 @@ func (repo *TicketRepository) Save(ctx context.Context, ticket *domain.Ticket) (
 			return err
 		}
 
-		log.Info().Msg("New ticket saved")
+		*ticket = savedTicket
+		logger.Info().Msg("New ticket saved")
 		return nil
 	}
 }
 
 func (repo *TicketRepository) FindByID(ctx context.Context, id int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "find_by_id")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket repository find by id")
-	log.Debug().Msg(fmt.Sprintf("Ticket repo find by id receive id with value %d", id))
+	logger.Trace().Msg("Inside ticket repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo find by id receive id with value %d", id))
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
-		return domain.Ticket{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch ticket")
-		
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
-
-		if err != nil {
-			return domain.Ticket{}, err
-		}
+		logger.Trace().Msg("Attempting to fetch ticket")
 
-		query := `
-			SELECT
-				t.ticket_id,
-				t.event_id,
-				t.name,
-				t.price,
-				t.stock,
-				t.type
-			FROM tickets t
-			WHERE
-				t.ticket_id=$1
-		`
-		log.Trace().Msg("Query is set")
+		query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ticket_id=$1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
 
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared Statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared Statement created with context")
 
 		if err != nil {
 			return domain.Ticket{}, err
@@ -171,60 +150,43 @@ func (repo *TicketRepository) FindByID(ctx context.Context, id int) (domain.Tick
 
 		defer stmt.Close()
 
-		var ticket domain.Ticket
-
-		errScan := stmt.QueryRowContext(ctx, id).Scan(
-			&ticket.TicketID,
-			&ticket.EventID,
-			&ticket.Name,
-			&ticket.Price,
-			&ticket.Stock,
-			&ticket.Type,
-		)
+		ticket, errScan := scanTicket(stmt.QueryRowContext(ctx, id))
 
 		if errScan != nil {
 			if errScan == sql.ErrNoRows {
-				log.Error().Msg(fmt.Sprintf("Ticket with ID %d not found", id))
-				return domain.Ticket{}, fmt.Errorf("no Ticket found with ID %d", id)
+				logger.Error().Msg(fmt.Sprintf("Ticket with ID %d not found", id))
+				return domain.Ticket{}, &domain.NotFoundError{Entity: "Ticket", ID: id}
 			}
-			return domain.Ticket{}, err
-		}
-
-		if err = trx.Commit(); err != nil {
-			return domain.Ticket{}, err
+			return domain.Ticket{}, errScan
 		}
 
-		log.Info().Msg("Ticket repo find by id completed")
+		logger.Info().Msg("Ticket repo find by id completed")
 
 		return ticket, nil
 	}
 }
 
 func (repo *TicketRepository) GetAll(ctx context.Context) ([]domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "get_all")
+	defer end()
 	repo.mtx.Lock()
 	defer repo.mtx.Unlock()
 
-	log.Trace().Msg("Inside ticket repository get all")
+	logger.Trace().Msg("Inside ticket repository get all")
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
-		return []domain.Ticket{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
+		return []domain.Ticket{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to fetch ticket")
+		logger.Trace().Msg("Attempting to fetch ticket")
 
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ` + notDeleted(ctx, "deleted_at") + ` ORDER BY ticket_id`
+		logger.Trace().Msg("Query is set")
 
-		if err != nil {
-			return []domain.Ticket{}, err
-		}
-
-		query := `SELECT ticket_id, event_id, name, price, type FROM tickets`
-		log.Trace().Msg("Query is set")
-
-		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		stmt, err := repo.db.PrepareContext(ctx, query)
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
 			return []domain.Ticket{}, err
@@ -233,7 +195,7 @@ func (repo *TicketRepository) GetAll(ctx context.Context) ([]domain.Ticket, erro
 		defer stmt.Close()
 
 		res, err := stmt.QueryContext(ctx)
-		log.Trace().Msg("Query ran")
+		logger.Trace().Msg("Query ran")
 
 		if err != nil {
 			return []domain.Ticket{}, err
@@ -244,85 +206,272 @@ func (repo *TicketRepository) GetAll(ctx context.Context) ([]domain.Ticket, erro
 		var listOfTickets []domain.Ticket
 
 		for res.Next() {
-			var ticket domain.Ticket
+			ticket, err := scanTicket(res)
 
-			res.Scan(&ticket.TicketID, &ticket.EventID, &ticket.Name, &ticket.Price, &ticket.Type)
+			if err != nil {
+				return []domain.Ticket{}, err
+			}
 
 			listOfTickets = append(listOfTickets, ticket)
 		}
-		
-		log.Info().Msg("Fetching completed")
+
+		if err := res.Err(); err != nil {
+			return []domain.Ticket{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
 		return listOfTickets, nil
 	}
 }
 
 func (repo *TicketRepository) Deduct(ctx context.Context, id int, amount int) (domain.Ticket, error) {
-	defer repo.mtx.Unlock()
-
-	log.Trace().Msg("Inside ticket repository deduct")
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "deduct")
+	defer end()
+	logger.Trace().Msg("Inside ticket repository deduct")
 
 	select {
 	case <- ctx.Done():
-		log.Error().Msg(fmt.Sprintf("Error when trying to deduct ticket because of timeout with message: %s", ctx.Err()))
-		return domain.Ticket{}, ctx.Err()
+		logger.Error().Msg(fmt.Sprintf("Error when trying to deduct ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
 	default:
-		log.Trace().Msg("Attempting to deduct ticket")
+		logger.Trace().Msg("Attempting to deduct ticket")
 
 		foundTicket, err := repo.FindByID(ctx, id)
-		log.Debug().Msg(fmt.Sprintf("Ticket ID found with ID %d", foundTicket.TicketID))
 
 		if err != nil {
 			return domain.Ticket{}, err
 		}
 
-		repo.mtx.Lock()
-		log.Debug().Msg(fmt.Sprintf("Stock before deduct: %d", foundTicket.Stock))
-		foundTicket.Stock = foundTicket.Stock - amount
+		logger.Debug().Msg(fmt.Sprintf("Ticket ID found with ID %d", foundTicket.TicketID))
+		logger.Debug().Msg(fmt.Sprintf("Stock before deduct: %d", foundTicket.Stock))
 
-		trx, err := repo.db.BeginTx(ctx, nil)
-		log.Trace().Msg("Begin transaction")
+		if foundTicket.Stock < amount {
+			return domain.Ticket{}, domain.ErrInsufficientStock
+		}
+
+		ticket, err := repo.setStock(ctx, foundTicket, foundTicket.Stock - amount)
 
 		if err != nil {
 			return domain.Ticket{}, err
 		}
 
+		logger.Debug().Msg(fmt.Sprintf("Stock after deduct: %d", ticket.Stock))
+		logger.Info().Msg("Successfully deduct stock")
+
+		return ticket, nil
+	}
+}
+
+func (repo *TicketRepository) Restore(ctx context.Context, id int, amount int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "restore")
+	defer end()
+	logger.Trace().Msg("Inside ticket repository restore")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to restore ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to restore ticket")
+
+		// stock of a ticket that was deleted after it was ordered can still be given back
+		foundTicket, err := repo.FindByID(domain.WithIncludeDeleted(ctx), id)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Stock before restore: %d", foundTicket.Stock))
+
+		ticket, err := repo.setStock(ctx, foundTicket, foundTicket.Stock + amount)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Stock after restore: %d", ticket.Stock))
+		logger.Info().Msg("Stock restored successfully")
+
+		return ticket, nil
+	}
+}
+
+// setStock writes a stock value computed from foundTicket, failing with a conflict if the ticket changed since it was read
+func (repo *TicketRepository) setStock(ctx context.Context, foundTicket domain.Ticket, stock int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	trx, err := repo.db.BeginTx(ctx, nil)
+	logger.Trace().Msg("Begin transaction")
+
+	if err != nil {
+		return domain.Ticket{}, err
+	}
+
+	defer trx.Rollback()
+
+	query := `
+		UPDATE
+			tickets
+		SET
+			stock=$1,
+			version=version+1,
+			updated_at=$2,
+			updated_by=$3
+		WHERE
+			ticket_id=$4
+			AND version=$5
+		RETURNING ` + ticketColumns
+	logger.Trace().Msg("Query is set")
+
+	stmt, err := trx.PrepareContext(ctx, query)
+	logger.Trace().Msg("Prepared statement created with context")
+
+	if err != nil {
+		return domain.Ticket{}, err
+	}
+
+	defer stmt.Close()
+
+	ticket, errScan := scanTicket(stmt.QueryRowContext(ctx, stock, time.Now().UTC(), domain.ActorFromContext(ctx), foundTicket.TicketID, foundTicket.Version))
+	logger.Trace().Msg("Query ran")
+
+	if errScan != nil {
+		if errScan == sql.ErrNoRows {
+			logger.Warn().Msg(fmt.Sprintf("Ticket with ID %d changed since version %d was read", foundTicket.TicketID, foundTicket.Version))
+			return domain.Ticket{}, &domain.ConflictError{Entity: "ticket", ID: foundTicket.TicketID, Version: foundTicket.Version}
+		}
+		return domain.Ticket{}, errScan
+	}
+
+	if err = trx.Commit(); err != nil {
+		return domain.Ticket{}, err
+	}
+
+	return ticket, nil
+}
+
+func (repo *TicketRepository) Update(ctx context.Context, ticket *domain.Ticket) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository update")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo update received id with value %d and version %d", ticket.TicketID, ticket.Version))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update ticket")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		logger.Trace().Msg("Checking if another ticket with the same Event ID and Type already exist")
+
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tickets WHERE event_id=$1 AND type=$2 AND ticket_id<>$3 AND deleted_at IS NULL`, ticket.EventID, ticket.Type, ticket.TicketID).Scan(&count)
+
+		if errCount != nil {
+			return errCount
+		}
+
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("ticket for Event ID %d with Type %s already exist", ticket.EventID, ticket.Type))
+			return fmt.Errorf("ticket with Event ID %d with Type %s %w", ticket.EventID, ticket.Type, domain.ErrAlreadyExists)
+		}
+
 		query := `
 			UPDATE
 				tickets
 			SET
-				stock=$1
+				event_id=$1,
+				name=$2,
+				price=$3,
+				stock=$4,
+				type=$5,
+				version=version+1,
+				updated_at=$6,
+				updated_by=$7
 			WHERE
-				ticket_id=$2
-			RETURNING
-				*
-		`
-		log.Trace().Msg("Query is set")
+				ticket_id=$8
+				AND version=$9
+				AND deleted_at IS NULL
+			RETURNING ` + ticketColumns
+		logger.Trace().Msg("Query is set")
 
 		stmt, err := trx.PrepareContext(ctx, query)
-		log.Trace().Msg("Prepared statement created with context")
+		logger.Trace().Msg("Prepared statement created with context")
 
 		if err != nil {
-			return domain.Ticket{}, err
+			return err
 		}
 
-		var ticket domain.Ticket
+		defer stmt.Close()
 
-		errScan := stmt.QueryRowContext(ctx, foundTicket.Stock, id).Scan(
-			&ticket.TicketID,
-			&ticket.EventID,
-			&ticket.Name,
-			&ticket.Price,
-			&ticket.Stock,
-			&ticket.Type,
-		)
+		updatedTicket, errScan := scanTicket(stmt.QueryRowContext(ctx, ticket.EventID, ticket.Name, ticket.Price, ticket.Stock, ticket.Type, time.Now().UTC(), domain.ActorFromContext(ctx), ticket.TicketID, ticket.Version))
+		logger.Trace().Msg("Query ran")
 
 		if errScan != nil {
-			return domain.Ticket{}, errScan
+			if errScan == sql.ErrNoRows {
+				return missingOrConflict(ctx, trx, "ticket", "SELECT COUNT(*) FROM tickets WHERE ticket_id=$1 AND deleted_at IS NULL", ticket.TicketID, ticket.Version)
+			}
+			return errScan
 		}
 
-		log.Debug().Msg(fmt.Sprintf("Stock after deduct: %d", ticket.Stock))
-		log.Info().Msg("Successfully deduct stock")
+		if err = trx.Commit(); err != nil {
+			return err
+		}
 
-		return ticket, nil
+		*ticket = updatedTicket
+		logger.Info().Msg("Ticket updated")
+		return nil
 	}
-}
\ No newline at end of file
+}
+
+func (repo *TicketRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete ticket")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE tickets SET deleted_at=$1, updated_at=$1, updated_by=$2, version=version+1 WHERE ticket_id=$3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Ticket with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Ticket", ID: id}
+		}
+
+		logger.Info().Msg("Ticket deleted")
+		return nil
+	}
+}
@@ -0,0 +1,377 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const apiKeyColumns = `key_id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, ` + auditColumns
+
+const apiKeyUsageColumns = `usage_id, key_id, method, path, remote_addr, used_at`
+
+type APIKeyRepository struct {
+	mtx sync.Mutex
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(database *sql.DB) repository.APIKeyRepositoryInterface {
+	return &APIKeyRepository{
+		db: database,
+	}
+}
+
+func scanAPIKey(row rowScanner) (domain.APIKey, error) {
+	var key domain.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&key.KeyID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+		&deletedAt,
+		&key.CreatedBy,
+		&key.UpdatedBy,
+	)
+	key.Scopes = strings.Split(scopes, ",")
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	key.DeletedAt = nullTime(deletedAt)
+
+	return key, err
+}
+
+func scanAPIKeyUsage(row rowScanner) (domain.APIKeyUsage, error) {
+	var usage domain.APIKeyUsage
+
+	err := row.Scan(
+		&usage.UsageID,
+		&usage.KeyID,
+		&usage.Method,
+		&usage.Path,
+		&usage.RemoteAddr,
+		&usage.UsedAt,
+	)
+
+	return usage, err
+}
+
+func (repo *APIKeyRepository) Save(ctx context.Context, key *domain.APIKey) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "save")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside api key repository save")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save api key because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to save new api key")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			INSERT INTO
+				api_keys
+				(user_id, name, prefix, key_hash, scopes, expires_at, created_at, updated_at, created_by, updated_by)
+			VALUES
+				(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?7, ?8, ?8)
+			RETURNING ` + apiKeyColumns
+		logger.Trace().Msg("Query is set")
+
+		var expiresAt sql.NullTime
+
+		if key.ExpiresAt != nil {
+			expiresAt = sql.NullTime{Time: key.ExpiresAt.UTC(), Valid: true}
+		}
+
+		savedKey, errScan := scanAPIKey(trx.QueryRowContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, ","), expiresAt, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		// the plain key only exists in memory, it is handed back to the caller once
+		savedKey.Key = key.Key
+		*key = savedKey
+		logger.Info().Msg("New api key saved")
+		return nil
+	}
+}
+
+func (repo *APIKeyRepository) FindByID(ctx context.Context, id int) (domain.APIKey, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "find_by_id")
+	defer end()
+	logger.Trace().Msg("Inside api key repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("API key repo find by id received id with value %d", id))
+
+	key, err := repo.find(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_id=?1 AND `+notDeleted(ctx, "deleted_at"), id)
+
+	if err == sql.ErrNoRows {
+		logger.Error().Msg(fmt.Sprintf("API key with ID %d not found", id))
+		return domain.APIKey{}, &domain.NotFoundError{Entity: "API key", ID: id}
+	}
+
+	return key, err
+}
+
+// FindByPrefix finds the key to check a presented key against, revoked keys are never found
+func (repo *APIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (domain.APIKey, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "find_by_prefix")
+	defer end()
+	logger.Trace().Msg("Inside api key repository find by prefix")
+
+	key, err := repo.find(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix=?1 AND deleted_at IS NULL`, prefix)
+
+	if err == sql.ErrNoRows {
+		logger.Error().Msg(fmt.Sprintf("API key with prefix %s not found", prefix))
+		return domain.APIKey{}, fmt.Errorf("no API key found with prefix %s: %w", prefix, domain.ErrNotFound)
+	}
+
+	return key, err
+}
+
+func (repo *APIKeyRepository) find(ctx context.Context, query string, arg any) (domain.APIKey, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch api key because of timeout with message: %s", ctx.Err()))
+		return domain.APIKey{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch api key")
+
+		key, errScan := scanAPIKey(repo.db.QueryRowContext(ctx, query, arg))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return domain.APIKey{}, errScan
+		}
+
+		logger.Info().Msg("API key repo find completed")
+		return key, nil
+	}
+}
+
+func (repo *APIKeyRepository) GetAll(ctx context.Context) ([]domain.APIKey, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "get_all")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside api key repository get all")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch api key because of timeout with message: %s", ctx.Err()))
+		return []domain.APIKey{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch api key")
+
+		query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE ` + notDeleted(ctx, "deleted_at") + ` ORDER BY key_id`
+		logger.Trace().Msg("Query is set")
+
+		res, err := repo.db.QueryContext(ctx, query)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.APIKey{}, err
+		}
+
+		defer res.Close()
+
+		var listOfKeys []domain.APIKey
+
+		for res.Next() {
+			key, err := scanAPIKey(res)
+
+			if err != nil {
+				return []domain.APIKey{}, err
+			}
+
+			listOfKeys = append(listOfKeys, key)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.APIKey{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfKeys, nil
+	}
+}
+
+// Delete revokes the key, its usage stays for auditing
+func (repo *APIKeyRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside api key repository delete")
+	logger.Debug().Msg(fmt.Sprintf("API key repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete api key because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to revoke api key")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE api_keys SET deleted_at=?1, updated_at=?1, updated_by=?2 WHERE key_id=?3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("API key with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "API key", ID: id}
+		}
+
+		logger.Info().Msg("API key revoked")
+		return nil
+	}
+}
+
+// RecordUsage stores one request made with a key and stamps the key's last use
+func (repo *APIKeyRepository) RecordUsage(ctx context.Context, usage *domain.APIKeyUsage) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "record_usage")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside api key repository record usage")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to record api key usage because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			INSERT INTO
+				api_key_usage
+				(key_id, method, path, remote_addr, used_at)
+			VALUES
+				(?1, ?2, ?3, ?4, ?5)
+			RETURNING ` + apiKeyUsageColumns
+		logger.Trace().Msg("Query is set")
+
+		savedUsage, errScan := scanAPIKeyUsage(trx.QueryRowContext(ctx, query, usage.KeyID, usage.Method, usage.Path, usage.RemoteAddr, usage.UsedAt.UTC()))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if _, err := trx.ExecContext(ctx, `UPDATE api_keys SET last_used_at=?1 WHERE key_id=?2`, usage.UsedAt.UTC(), usage.KeyID); err != nil {
+			return err
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*usage = savedUsage
+		logger.Debug().Msg(fmt.Sprintf("Usage of api key %d recorded", usage.KeyID))
+		return nil
+	}
+}
+
+func (repo *APIKeyRepository) GetUsage(ctx context.Context, keyID int) ([]domain.APIKeyUsage, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "api_key", "get_usage")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside api key repository get usage")
+	logger.Debug().Msg(fmt.Sprintf("API key repo get usage received id with value %d", keyID))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch api key usage because of timeout with message: %s", ctx.Err()))
+		return []domain.APIKeyUsage{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch api key usage")
+
+		query := `SELECT ` + apiKeyUsageColumns + ` FROM api_key_usage WHERE key_id=?1 ORDER BY used_at DESC, usage_id DESC`
+		logger.Trace().Msg("Query is set")
+
+		res, err := repo.db.QueryContext(ctx, query, keyID)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.APIKeyUsage{}, err
+		}
+
+		defer res.Close()
+
+		var listOfUsage []domain.APIKeyUsage
+
+		for res.Next() {
+			usage, err := scanAPIKeyUsage(res)
+
+			if err != nil {
+				return []domain.APIKeyUsage{}, err
+			}
+
+			listOfUsage = append(listOfUsage, usage)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.APIKeyUsage{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfUsage, nil
+	}
+}
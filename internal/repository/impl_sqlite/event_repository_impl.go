@@ -0,0 +1,310 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const eventColumns = `event_id, event_name, organizer_id, version, ` + auditColumns
+
+type EventRepository struct {
+	mtx sync.Mutex
+	db *sql.DB
+}
+
+func NewEventRepository(database *sql.DB) repository.EventRepositoryInterface {
+	return &EventRepository{
+		db: database,
+	}
+}
+
+func scanEvent(row rowScanner) (domain.Event, error) {
+	var event domain.Event
+	var organizerID sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&event.EventID,
+		&event.EventName,
+		&organizerID,
+		&event.Version,
+		&event.CreatedAt,
+		&event.UpdatedAt,
+		&deletedAt,
+		&event.CreatedBy,
+		&event.UpdatedBy,
+	)
+	event.OrganizerID = nullInt(organizerID)
+	event.DeletedAt = nullTime(deletedAt)
+
+	return event, err
+}
+
+func (repo *EventRepository) Save(ctx context.Context, event *domain.Event) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "save")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository save")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to save new event")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			INSERT INTO
+				events
+				(event_name, organizer_id, created_at, updated_at, created_by, updated_by)
+			VALUES
+				(?1, ?2, ?3, ?3, ?4, ?4)
+			RETURNING ` + eventColumns
+		logger.Trace().Msg("Query is set")
+
+		savedEvent, errScan := scanEvent(trx.QueryRowContext(ctx, query, event.EventName, nullID(event.OrganizerID), time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*event = savedEvent
+		logger.Info().Msg("New event saved")
+		return nil
+	}
+}
+
+func (repo *EventRepository) FindByID(ctx context.Context, id int) (domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "find_by_id")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Event repo find by id received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
+		return domain.Event{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch event")
+
+		query := `SELECT ` + eventColumns + ` FROM events WHERE event_id=?1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
+
+		event, errScan := scanEvent(repo.db.QueryRowContext(ctx, query, id))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("Event with ID %d not found", id))
+				return domain.Event{}, &domain.NotFoundError{Entity: "Event", ID: id}
+			}
+			return domain.Event{}, errScan
+		}
+
+		logger.Info().Msg("Event repo find by id completed")
+		return event, nil
+	}
+}
+
+func (repo *EventRepository) GetAll(ctx context.Context) ([]domain.Event, error) {
+	ctx, end := observe(ctx, "event", "get_all")
+	defer end()
+	zerolog.Ctx(ctx).Trace().Msg("Inside event repository get all")
+	return repo.list(ctx, `SELECT `+eventColumns+` FROM events WHERE `+notDeleted(ctx, "deleted_at")+` ORDER BY event_id`)
+}
+
+func (repo *EventRepository) GetByOrganizerID(ctx context.Context, organizerID int) ([]domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "get_by_organizer_id")
+	defer end()
+	logger.Trace().Msg("Inside event repository get by organizer id")
+	logger.Debug().Msg(fmt.Sprintf("Event repo get by organizer id received id with value %d", organizerID))
+	return repo.list(ctx, `SELECT `+eventColumns+` FROM events WHERE organizer_id=?1 AND `+notDeleted(ctx, "deleted_at")+` ORDER BY event_id`, organizerID)
+}
+
+func (repo *EventRepository) list(ctx context.Context, query string, args ...any) ([]domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch event because of timeout with message: %s", ctx.Err()))
+		return []domain.Event{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch event")
+
+		res, err := repo.db.QueryContext(ctx, query, args...)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.Event{}, err
+		}
+
+		defer res.Close()
+
+		var listOfEvents []domain.Event
+
+		for res.Next() {
+			event, err := scanEvent(res)
+
+			if err != nil {
+				return []domain.Event{}, err
+			}
+
+			listOfEvents = append(listOfEvents, event)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.Event{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfEvents, nil
+	}
+}
+
+func (repo *EventRepository) Update(ctx context.Context, event *domain.Event) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository update")
+	logger.Debug().Msg(fmt.Sprintf("Event repo update received id with value %d and version %d", event.EventID, event.Version))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update event")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			UPDATE
+				events
+			SET
+				event_name=?1,
+				organizer_id=?2,
+				version=version+1,
+				updated_at=?3,
+				updated_by=?4
+			WHERE
+				event_id=?5
+				AND version=?6
+				AND deleted_at IS NULL
+			RETURNING ` + eventColumns
+		logger.Trace().Msg("Query is set")
+
+		updatedEvent, errScan := scanEvent(trx.QueryRowContext(ctx, query, event.EventName, nullID(event.OrganizerID), time.Now().UTC(), domain.ActorFromContext(ctx), event.EventID, event.Version))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				return missingOrConflict(ctx, trx, "event", "SELECT COUNT(*) FROM events WHERE event_id=?1 AND deleted_at IS NULL", event.EventID, event.Version)
+			}
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*event = updatedEvent
+		logger.Info().Msg("Event updated")
+		return nil
+	}
+}
+
+func (repo *EventRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "event", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside event repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Event repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete event because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete event and its tickets")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		now := time.Now().UTC()
+		actor := domain.ActorFromContext(ctx)
+
+		res, err := trx.ExecContext(ctx, `UPDATE events SET deleted_at=?1, updated_at=?1, updated_by=?2, version=version+1 WHERE event_id=?3 AND deleted_at IS NULL`, now, actor, id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Event with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Event", ID: id}
+		}
+
+		if _, err := trx.ExecContext(ctx, `UPDATE tickets SET deleted_at=?1, updated_at=?1, updated_by=?2, version=version+1 WHERE event_id=?3 AND deleted_at IS NULL`, now, actor, id); err != nil {
+			return err
+		}
+		logger.Trace().Msg("Tickets of the event deleted")
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		logger.Info().Msg("Event deleted")
+		return nil
+	}
+}
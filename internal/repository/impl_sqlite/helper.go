@@ -0,0 +1,85 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/provider/metrics"
+	"ticket_goroutine/internal/provider/tracing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// dbSystem names the database in the spans of repository operations
+const dbSystem = "sqlite"
+
+// auditColumns are selected after each table's own columns
+const auditColumns = `created_at, updated_at, deleted_at, created_by, updated_by`
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
+
+func nullInt(value sql.NullInt64) int {
+	return int(value.Int64)
+}
+
+// nullID stores an unset reference, the zero ID, as NULL
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// qualified prefixes every column of a column list with the table alias, for queries joining tables
+func qualified(alias string, columns string) string {
+	return alias + "." + strings.ReplaceAll(columns, ", ", ", "+alias+".")
+}
+
+// notDeleted is the soft delete condition on column, dropped when the context asks for deleted rows too
+func notDeleted(ctx context.Context, column string) string {
+	if domain.IncludeDeleted(ctx) {
+		return "1=1"
+	}
+
+	return fmt.Sprintf("%s IS NULL", column)
+}
+
+// missingOrConflict explains why a versioned update matched no row: either the row is gone or its version moved on
+func missingOrConflict(ctx context.Context, trx *sql.Tx, entity string, countQuery string, id int, version int) error {
+	logger := zerolog.Ctx(ctx)
+	var count int
+
+	if err := trx.QueryRowContext(ctx, countQuery, id).Scan(&count); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		logger.Error().Msg(fmt.Sprintf("%s with ID %d not found", entity, id))
+		return &domain.NotFoundError{Entity: entity, ID: id}
+	}
+
+	logger.Warn().Msg(fmt.Sprintf("%s with ID %d changed since version %d was read", entity, id, version))
+	return &domain.ConflictError{Entity: entity, ID: id, Version: version}
+}
+
+// observe traces a repository operation as a span and times it, the returned func ends both
+func observe(ctx context.Context, repository string, operation string) (context.Context, func()) {
+	done := metrics.ObserveRepository(repository, operation)
+	ctx, span := tracing.Start(ctx, repository+"."+operation, attribute.String("db.system", dbSystem), attribute.String("db.operation", operation))
+
+	return ctx, func() {
+		span.End()
+		done()
+	}
+}
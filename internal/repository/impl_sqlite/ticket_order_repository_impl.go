@@ -0,0 +1,340 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const ticketOrderColumns = `order_id, ticket_id, user_id, amount, total_price, ` + auditColumns
+
+type TicketOrderRepository struct {
+	mtx sync.Mutex
+	db *sql.DB
+}
+
+func NewTicketOrderRepository(database *sql.DB) repository.TicketOrderRepositoryInterface {
+	return &TicketOrderRepository {
+		db: database,
+	}
+}
+
+func scanTicketOrder(row rowScanner) (domain.TicketOrder, error) {
+	var ticketOrder domain.TicketOrder
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&ticketOrder.OrderID,
+		&ticketOrder.TicketID,
+		&ticketOrder.UserID,
+		&ticketOrder.Amount,
+		&ticketOrder.TotalPrice,
+		&ticketOrder.CreatedAt,
+		&ticketOrder.UpdatedAt,
+		&deletedAt,
+		&ticketOrder.CreatedBy,
+		&ticketOrder.UpdatedBy,
+	)
+	ticketOrder.DeletedAt = nullTime(deletedAt)
+
+	return ticketOrder, err
+}
+
+func (repo *TicketOrderRepository) Save(ctx context.Context, ticketOrder *domain.TicketOrder) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "save")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository save")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to save new ticket order")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		query := `
+			INSERT INTO
+				ticket_order
+				(ticket_id, user_id, amount, total_price, created_at, updated_at, created_by, updated_by)
+			VALUES
+				(?1, ?2, ?3, ?4, ?5, ?5, ?6, ?6)
+			RETURNING ` + ticketOrderColumns
+		logger.Trace().Msg("Query is set")
+
+		savedTicketOrder, errScan := scanTicketOrder(trx.QueryRowContext(ctx, query, ticketOrder.TicketID, ticketOrder.UserID, ticketOrder.Amount, ticketOrder.TotalPrice, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*ticketOrder = savedTicketOrder
+		logger.Info().Msg("New ticket order saved")
+		return nil
+	}
+}
+
+func (repo *TicketOrderRepository) FindByID(ctx context.Context, id int) (domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "find_by_id")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo find by id received id value is %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.TicketOrder{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch ticket order")
+
+		query := `SELECT ` + ticketOrderColumns + ` FROM ticket_order WHERE order_id=?1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
+
+		ticketOrder, errScan := scanTicketOrder(repo.db.QueryRowContext(ctx, query, id))
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", id))
+				return domain.TicketOrder{}, &domain.NotFoundError{Entity: "Ticket order", ID: id}
+			}
+			return domain.TicketOrder{}, errScan
+		}
+
+		logger.Info().Msg("Ticket order repo find by id completed")
+		return ticketOrder, nil
+	}
+}
+
+func (repo *TicketOrderRepository) GetAll(ctx context.Context) ([]domain.TicketOrder, error) {
+	ctx, end := observe(ctx, "ticket_order", "get_all")
+	defer end()
+	zerolog.Ctx(ctx).Trace().Msg("Inside ticket order repository get all")
+	return repo.list(ctx, `SELECT `+ticketOrderColumns+` FROM ticket_order WHERE `+notDeleted(ctx, "deleted_at")+` ORDER BY order_id`)
+}
+
+// GetByOrganizerID lists the orders placed for tickets of the organizer's events
+func (repo *TicketOrderRepository) GetByOrganizerID(ctx context.Context, organizerID int) ([]domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "get_by_organizer_id")
+	defer end()
+	logger.Trace().Msg("Inside ticket order repository get by organizer id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo get by organizer id received id with value %d", organizerID))
+
+	query := `
+		SELECT ` + qualified("o", ticketOrderColumns) + `
+		FROM ticket_order o
+		JOIN tickets t
+		ON o.ticket_id=t.ticket_id
+		JOIN events e
+		ON t.event_id=e.event_id
+		WHERE
+			e.organizer_id=?1
+			AND ` + notDeleted(ctx, "o.deleted_at") + `
+		ORDER BY o.order_id
+	`
+
+	return repo.list(ctx, query, organizerID)
+}
+
+func (repo *TicketOrderRepository) list(ctx context.Context, query string, args ...any) ([]domain.TicketOrder, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket order because of timeout with message: %s", ctx.Err()))
+		return []domain.TicketOrder{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch ticket order")
+
+		res, err := repo.db.QueryContext(ctx, query, args...)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.TicketOrder{}, err
+		}
+
+		defer res.Close()
+
+		var listOfTicketOrders []domain.TicketOrder
+
+		for res.Next() {
+			ticketOrder, err := scanTicketOrder(res)
+
+			if err != nil {
+				return []domain.TicketOrder{}, err
+			}
+
+			listOfTicketOrders = append(listOfTicketOrders, ticketOrder)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.TicketOrder{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfTicketOrders, nil
+	}
+}
+
+func (repo *TicketOrderRepository) Update(ctx context.Context, ticketOrder *domain.TicketOrder) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository update")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo update received id value is %d", ticketOrder.OrderID))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update ticket order")
+
+		query := `
+			UPDATE
+				ticket_order
+			SET
+				amount=?1,
+				total_price=?2,
+				updated_at=?3,
+				updated_by=?4
+			WHERE
+				order_id=?5
+				AND deleted_at IS NULL
+			RETURNING ` + ticketOrderColumns
+		logger.Trace().Msg("Query is set")
+
+		updatedTicketOrder, errScan := scanTicketOrder(repo.db.QueryRowContext(ctx, query, ticketOrder.Amount, ticketOrder.TotalPrice, time.Now().UTC(), domain.ActorFromContext(ctx), ticketOrder.OrderID))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", ticketOrder.OrderID))
+				return &domain.NotFoundError{Entity: "Ticket order", ID: ticketOrder.OrderID}
+			}
+			return errScan
+		}
+
+		*ticketOrder = updatedTicketOrder
+		logger.Info().Msg("Ticket order updated")
+		return nil
+	}
+}
+
+func (repo *TicketOrderRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket_order", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Ticket order repo delete received id value is %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete ticket order because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete ticket order")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE ticket_order SET deleted_at=?1, updated_at=?1, updated_by=?2 WHERE order_id=?3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Ticket order with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Ticket order", ID: id}
+		}
+
+		logger.Info().Msg("Ticket order deleted")
+		return nil
+	}
+}
+
+func (repo *TicketOrderRepository) CountByEventID(ctx context.Context, eventID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_event_id")
+	defer end()
+	query := `
+		SELECT
+			COUNT(*)
+		FROM ticket_order o
+		JOIN tickets t
+		ON o.ticket_id=t.ticket_id
+		WHERE
+			t.event_id=?1
+			AND o.deleted_at IS NULL
+	`
+
+	return repo.count(ctx, query, eventID)
+}
+
+func (repo *TicketOrderRepository) CountByTicketID(ctx context.Context, ticketID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_ticket_id")
+	defer end()
+	return repo.count(ctx, `SELECT COUNT(*) FROM ticket_order WHERE ticket_id=?1 AND deleted_at IS NULL`, ticketID)
+}
+
+func (repo *TicketOrderRepository) CountByUserID(ctx context.Context, userID int) (int, error) {
+	ctx, end := observe(ctx, "ticket_order", "count_by_user_id")
+	defer end()
+	return repo.count(ctx, `SELECT COUNT(*) FROM ticket_order WHERE user_id=?1 AND deleted_at IS NULL`, userID)
+}
+
+func (repo *TicketOrderRepository) count(ctx context.Context, query string, id int) (int, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket order repository count")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to count ticket order because of timeout with message: %s", ctx.Err()))
+		return 0, domain.Timeout(ctx.Err())
+	default:
+		var count int
+
+		if err := repo.db.QueryRowContext(ctx, query, id).Scan(&count); err != nil {
+			return 0, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Ticket order count is %d", count))
+		return count, nil
+	}
+}
@@ -0,0 +1,432 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const ticketColumns = `ticket_id, event_id, name, price, stock, type, version, ` + auditColumns
+
+type TicketRepository struct {
+	mtx sync.Mutex
+	db *sql.DB
+}
+
+func NewTicketRepository(database *sql.DB) repository.TicketRepositoryInterface {
+	return &TicketRepository{
+		db: database,
+	}
+}
+
+func scanTicket(row rowScanner) (domain.Ticket, error) {
+	var ticket domain.Ticket
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&ticket.TicketID,
+		&ticket.EventID,
+		&ticket.Name,
+		&ticket.Price,
+		&ticket.Stock,
+		&ticket.Type,
+		&ticket.Version,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+		&deletedAt,
+		&ticket.CreatedBy,
+		&ticket.UpdatedBy,
+	)
+	ticket.DeletedAt = nullTime(deletedAt)
+
+	return ticket, err
+}
+
+func (repo *TicketRepository) Save(ctx context.Context, ticket *domain.Ticket) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "save")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository save")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to save new ticket")
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		logger.Trace().Msg("Checking if ticket with the same Event ID and Type already exist")
+
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tickets WHERE event_id=?1 AND type=?2 AND deleted_at IS NULL`, ticket.EventID, ticket.Type).Scan(&count)
+
+		if errCount != nil {
+			return errCount
+		}
+
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("ticket for Event ID %d with Type %s already exist", ticket.EventID, ticket.Type))
+			return fmt.Errorf("ticket with Event ID %d with Type %s %w", ticket.EventID, ticket.Type, domain.ErrAlreadyExists)
+		}
+
+		query := `
+			INSERT INTO
+				tickets
+				(event_id, name, price, stock, type, created_at, updated_at, created_by, updated_by)
+			VALUES
+				(?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7, ?7)
+			RETURNING ` + ticketColumns
+		logger.Trace().Msg("Query is set")
+
+		savedTicket, errScan := scanTicket(trx.QueryRowContext(ctx, query, ticket.EventID, ticket.Name, ticket.Price, ticket.Stock, ticket.Type, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*ticket = savedTicket
+		logger.Info().Msg("New ticket saved")
+		return nil
+	}
+}
+
+func (repo *TicketRepository) FindByID(ctx context.Context, id int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "find_by_id")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo find by id receive id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch ticket")
+
+		query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ticket_id=?1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
+
+		ticket, errScan := scanTicket(repo.db.QueryRowContext(ctx, query, id))
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("Ticket with ID %d not found", id))
+				return domain.Ticket{}, &domain.NotFoundError{Entity: "Ticket", ID: id}
+			}
+			return domain.Ticket{}, errScan
+		}
+
+		logger.Info().Msg("Ticket repo find by id completed")
+
+		return ticket, nil
+	}
+}
+
+func (repo *TicketRepository) GetAll(ctx context.Context) ([]domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "get_all")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository get all")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch ticket because of timeout with message: %s", ctx.Err()))
+		return []domain.Ticket{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch ticket")
+
+		query := `SELECT ` + ticketColumns + ` FROM tickets WHERE ` + notDeleted(ctx, "deleted_at") + ` ORDER BY ticket_id`
+		logger.Trace().Msg("Query is set")
+
+		res, err := repo.db.QueryContext(ctx, query)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.Ticket{}, err
+		}
+
+		defer res.Close()
+
+		var listOfTickets []domain.Ticket
+
+		for res.Next() {
+			ticket, err := scanTicket(res)
+
+			if err != nil {
+				return []domain.Ticket{}, err
+			}
+
+			listOfTickets = append(listOfTickets, ticket)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.Ticket{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfTickets, nil
+	}
+}
+
+func (repo *TicketRepository) Deduct(ctx context.Context, id int, amount int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "deduct")
+	defer end()
+	logger.Trace().Msg("Inside ticket repository deduct")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to deduct ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to deduct ticket")
+
+		foundTicket, err := repo.FindByID(ctx, id)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Ticket ID found with ID %d", foundTicket.TicketID))
+		logger.Debug().Msg(fmt.Sprintf("Stock before deduct: %d", foundTicket.Stock))
+
+		if foundTicket.Stock < amount {
+			return domain.Ticket{}, domain.ErrInsufficientStock
+		}
+
+		ticket, err := repo.setStock(ctx, foundTicket, foundTicket.Stock - amount)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Stock after deduct: %d", ticket.Stock))
+		logger.Info().Msg("Successfully deduct stock")
+
+		return ticket, nil
+	}
+}
+
+func (repo *TicketRepository) Restore(ctx context.Context, id int, amount int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "restore")
+	defer end()
+	logger.Trace().Msg("Inside ticket repository restore")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to restore ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Ticket{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to restore ticket")
+
+		// stock of a ticket that was deleted after it was ordered can still be given back
+		foundTicket, err := repo.FindByID(domain.WithIncludeDeleted(ctx), id)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Stock before restore: %d", foundTicket.Stock))
+
+		ticket, err := repo.setStock(ctx, foundTicket, foundTicket.Stock + amount)
+
+		if err != nil {
+			return domain.Ticket{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Stock after restore: %d", ticket.Stock))
+		logger.Info().Msg("Stock restored successfully")
+
+		return ticket, nil
+	}
+}
+
+// setStock writes a stock value computed from foundTicket, failing with a conflict if the ticket changed since it was read
+func (repo *TicketRepository) setStock(ctx context.Context, foundTicket domain.Ticket, stock int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	trx, err := repo.db.BeginTx(ctx, nil)
+	logger.Trace().Msg("Begin transaction")
+
+	if err != nil {
+		return domain.Ticket{}, err
+	}
+
+	defer trx.Rollback()
+
+	query := `
+		UPDATE
+			tickets
+		SET
+			stock=?1,
+			version=version+1,
+			updated_at=?2,
+			updated_by=?3
+		WHERE
+			ticket_id=?4
+			AND version=?5
+		RETURNING ` + ticketColumns
+	logger.Trace().Msg("Query is set")
+
+	ticket, errScan := scanTicket(trx.QueryRowContext(ctx, query, stock, time.Now().UTC(), domain.ActorFromContext(ctx), foundTicket.TicketID, foundTicket.Version))
+	logger.Trace().Msg("Query ran")
+
+	if errScan != nil {
+		if errScan == sql.ErrNoRows {
+			logger.Warn().Msg(fmt.Sprintf("Ticket with ID %d changed since version %d was read", foundTicket.TicketID, foundTicket.Version))
+			return domain.Ticket{}, &domain.ConflictError{Entity: "ticket", ID: foundTicket.TicketID, Version: foundTicket.Version}
+		}
+		return domain.Ticket{}, errScan
+	}
+
+	if err = trx.Commit(); err != nil {
+		return domain.Ticket{}, err
+	}
+
+	return ticket, nil
+}
+
+func (repo *TicketRepository) Update(ctx context.Context, ticket *domain.Ticket) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository update")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo update received id with value %d and version %d", ticket.TicketID, ticket.Version))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update ticket")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		logger.Trace().Msg("Checking if another ticket with the same Event ID and Type already exist")
+
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tickets WHERE event_id=?1 AND type=?2 AND ticket_id<>?3 AND deleted_at IS NULL`, ticket.EventID, ticket.Type, ticket.TicketID).Scan(&count)
+
+		if errCount != nil {
+			return errCount
+		}
+
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("ticket for Event ID %d with Type %s already exist", ticket.EventID, ticket.Type))
+			return fmt.Errorf("ticket with Event ID %d with Type %s %w", ticket.EventID, ticket.Type, domain.ErrAlreadyExists)
+		}
+
+		query := `
+			UPDATE
+				tickets
+			SET
+				event_id=?1,
+				name=?2,
+				price=?3,
+				stock=?4,
+				type=?5,
+				version=version+1,
+				updated_at=?6,
+				updated_by=?7
+			WHERE
+				ticket_id=?8
+				AND version=?9
+				AND deleted_at IS NULL
+			RETURNING ` + ticketColumns
+		logger.Trace().Msg("Query is set")
+
+		updatedTicket, errScan := scanTicket(trx.QueryRowContext(ctx, query, ticket.EventID, ticket.Name, ticket.Price, ticket.Stock, ticket.Type, time.Now().UTC(), domain.ActorFromContext(ctx), ticket.TicketID, ticket.Version))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				return missingOrConflict(ctx, trx, "ticket", "SELECT COUNT(*) FROM tickets WHERE ticket_id=?1 AND deleted_at IS NULL", ticket.TicketID, ticket.Version)
+			}
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*ticket = updatedTicket
+		logger.Info().Msg("Ticket updated")
+		return nil
+	}
+}
+
+func (repo *TicketRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "ticket", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside ticket repository delete")
+	logger.Debug().Msg(fmt.Sprintf("Ticket repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete ticket because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete ticket")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE tickets SET deleted_at=?1, updated_at=?1, updated_by=?2, version=version+1 WHERE ticket_id=?3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("Ticket with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "Ticket", ID: id}
+		}
+
+		logger.Info().Msg("Ticket deleted")
+		return nil
+	}
+}
@@ -0,0 +1,467 @@
+package implsqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const userColumns = `user_id, email, name, phone_number, balance, password_hash, role, version, ` + auditColumns
+
+type UserRepository struct {
+	mtx sync.Mutex
+	db *sql.DB
+}
+
+func NewUserRepository(database *sql.DB) repository.UserRepositoryInterface {
+	return &UserRepository{
+		db: database,
+	}
+}
+
+func scanUser(row rowScanner) (domain.User, error) {
+	var user domain.User
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&user.UserID,
+		&user.Email,
+		&user.Name,
+		&user.PhoneNumber,
+		&user.Balance,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&deletedAt,
+		&user.CreatedBy,
+		&user.UpdatedBy,
+	)
+	user.DeletedAt = nullTime(deletedAt)
+
+	return user, err
+}
+
+func (repo *UserRepository) Save(ctx context.Context, user *domain.User) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "save")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository save")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to save user because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to save new user")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		logger.Trace().Msg("Checking if user with the same email already exist")
+
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email=?1 AND deleted_at IS NULL`, user.Email).Scan(&count)
+
+		if errCount != nil {
+			return errCount
+		}
+
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("user with email %s already exist", user.Email))
+			return fmt.Errorf("user with email %s %w", user.Email, domain.ErrAlreadyExists)
+		}
+
+		query := `
+			INSERT INTO
+				users
+				(email, name, phone_number, balance, password_hash, role, created_at, updated_at, created_by, updated_by)
+			VALUES
+				(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?7, ?8, ?8)
+			RETURNING ` + userColumns
+		logger.Trace().Msg("Query is set")
+
+		savedUser, errScan := scanUser(trx.QueryRowContext(ctx, query, user.Email, user.Name, user.PhoneNumber, user.Balance, user.PasswordHash, user.Role, time.Now().UTC(), domain.ActorFromContext(ctx)))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*user = savedUser
+		logger.Info().Msg("New user saved")
+		return nil
+	}
+}
+
+func (repo *UserRepository) FindByID(ctx context.Context, id int) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "find_by_id")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository find by id")
+	logger.Debug().Msg(fmt.Sprintf("User repo find by id received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch user because of timeout with message: %s", ctx.Err()))
+		return domain.User{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch user")
+
+		query := `SELECT ` + userColumns + ` FROM users WHERE user_id=?1 AND ` + notDeleted(ctx, "deleted_at")
+		logger.Trace().Msg("Query is set")
+
+		user, errScan := scanUser(repo.db.QueryRowContext(ctx, query, id))
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg(fmt.Sprintf("User with ID %d not found", id))
+				return domain.User{}, &domain.NotFoundError{Entity: "user", ID: id}
+			}
+			return domain.User{}, errScan
+		}
+
+		logger.Info().Msg("User repo find by id completed")
+		return user, nil
+	}
+}
+
+func (repo *UserRepository) FindByEmail(ctx context.Context, email string) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "find_by_email")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository find by email")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch user because of timeout with message: %s", ctx.Err()))
+		return domain.User{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch user by email")
+
+		query := `SELECT ` + userColumns + ` FROM users WHERE email=?1 AND deleted_at IS NULL`
+		logger.Trace().Msg("Query is set")
+
+		user, errScan := scanUser(repo.db.QueryRowContext(ctx, query, email))
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				logger.Error().Msg("User with the given email not found")
+				return domain.User{}, fmt.Errorf("no user found with the given email: %w", domain.ErrNotFound)
+			}
+			return domain.User{}, errScan
+		}
+
+		logger.Info().Msg("User repo find by email completed")
+		return user, nil
+	}
+}
+
+func (repo *UserRepository) GetAll(ctx context.Context) ([]domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "get_all")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository get all")
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to fetch user because of timeout with message: %s", ctx.Err()))
+		return []domain.User{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to fetch user")
+
+		query := `SELECT ` + userColumns + ` FROM users WHERE ` + notDeleted(ctx, "deleted_at") + ` ORDER BY user_id`
+		logger.Trace().Msg("Query is set")
+
+		res, err := repo.db.QueryContext(ctx, query)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return []domain.User{}, err
+		}
+
+		defer res.Close()
+
+		var listOfUsers []domain.User
+
+		for res.Next() {
+			user, err := scanUser(res)
+
+			if err != nil {
+				return []domain.User{}, err
+			}
+
+			listOfUsers = append(listOfUsers, user)
+		}
+
+		if err := res.Err(); err != nil {
+			return []domain.User{}, err
+		}
+
+		logger.Info().Msg("Fetching completed")
+		return listOfUsers, nil
+	}
+}
+
+func (repo *UserRepository) ReduceBalance(ctx context.Context, id int, amount float64) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "reduce_balance")
+	defer end()
+	logger.Trace().Msg("Inside user repository reduce balance")
+	logger.Debug().Msg(fmt.Sprintf("User repo reduce balance received id with value %d and amount %f", id, amount))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to reduce user balance because of timeout with message: %s", ctx.Err()))
+		return domain.User{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to reduce user balance")
+		foundUser, err := repo.FindByID(ctx, id)
+
+		if err != nil {
+			return domain.User{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Balance before reduced: %f", foundUser.Balance))
+
+		if foundUser.Balance < amount {
+			return domain.User{}, domain.ErrInsufficientBalance
+		}
+
+		user, err := repo.setBalance(ctx, foundUser, foundUser.Balance - amount)
+
+		if err != nil {
+			return domain.User{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Balance after reduced: %f", user.Balance))
+		logger.Info().Msg("Balance reduced successfully")
+		return user, nil
+	}
+}
+
+func (repo *UserRepository) AddBalance(ctx context.Context, id int, amount float64) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "add_balance")
+	defer end()
+	logger.Trace().Msg("Inside user repository add balance")
+	logger.Debug().Msg(fmt.Sprintf("User repo add balance received id with value %d and amount %f", id, amount))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to add user balance because of timeout with message: %s", ctx.Err()))
+		return domain.User{}, domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to add user balance")
+
+		// refunds still reach a user that was deleted after ordering
+		foundUser, err := repo.FindByID(domain.WithIncludeDeleted(ctx), id)
+
+		if err != nil {
+			return domain.User{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Balance before added: %f", foundUser.Balance))
+
+		user, err := repo.setBalance(ctx, foundUser, foundUser.Balance + amount)
+
+		if err != nil {
+			return domain.User{}, err
+		}
+
+		logger.Debug().Msg(fmt.Sprintf("Balance after added: %f", user.Balance))
+		logger.Info().Msg("Balance added successfully")
+		return user, nil
+	}
+}
+
+// setBalance writes a balance computed from foundUser, failing with a conflict if the user changed since it was read
+func (repo *UserRepository) setBalance(ctx context.Context, foundUser domain.User, balance float64) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	trx, err := repo.db.BeginTx(ctx, nil)
+	logger.Trace().Msg("Begin transaction")
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	defer trx.Rollback()
+
+	query := `
+		UPDATE
+			users
+		SET
+			balance=?1,
+			version=version+1,
+			updated_at=?2,
+			updated_by=?3
+		WHERE
+			user_id=?4
+			AND version=?5
+		RETURNING ` + userColumns
+	logger.Trace().Msg("Query is set")
+
+	user, errScan := scanUser(trx.QueryRowContext(ctx, query, balance, time.Now().UTC(), domain.ActorFromContext(ctx), foundUser.UserID, foundUser.Version))
+	logger.Trace().Msg("Query ran")
+
+	if errScan != nil {
+		if errScan == sql.ErrNoRows {
+			logger.Warn().Msg(fmt.Sprintf("User with ID %d changed since version %d was read", foundUser.UserID, foundUser.Version))
+			return domain.User{}, &domain.ConflictError{Entity: "user", ID: foundUser.UserID, Version: foundUser.Version}
+		}
+		return domain.User{}, errScan
+	}
+
+	if err = trx.Commit(); err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+func (repo *UserRepository) Update(ctx context.Context, user *domain.User) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "update")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository update")
+	logger.Debug().Msg(fmt.Sprintf("User repo update received id with value %d and version %d", user.UserID, user.Version))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to update user because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to update user")
+
+		trx, err := repo.db.BeginTx(ctx, nil)
+		logger.Trace().Msg("Begin transaction")
+
+		if err != nil {
+			return err
+		}
+
+		defer trx.Rollback()
+
+		logger.Trace().Msg("Checking if another user already has the email")
+
+		var count int
+		errCount := trx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email=?1 AND user_id<>?2 AND deleted_at IS NULL`, user.Email, user.UserID).Scan(&count)
+
+		if errCount != nil {
+			return errCount
+		}
+
+		if count > 0 {
+			logger.Error().Msg(fmt.Sprintf("user with email %s already exist", user.Email))
+			return fmt.Errorf("user with email %s %w", user.Email, domain.ErrAlreadyExists)
+		}
+
+		// an empty password_hash keeps the current one, the hash is only replaced when a new password was given
+		query := `
+			UPDATE
+				users
+			SET
+				email=?1,
+				name=?2,
+				phone_number=?3,
+				balance=?4,
+				password_hash=COALESCE(NULLIF(?5, ''), password_hash),
+				role=?6,
+				version=version+1,
+				updated_at=?7,
+				updated_by=?8
+			WHERE
+				user_id=?9
+				AND version=?10
+				AND deleted_at IS NULL
+			RETURNING ` + userColumns
+		logger.Trace().Msg("Query is set")
+
+		updatedUser, errScan := scanUser(trx.QueryRowContext(ctx, query, user.Email, user.Name, user.PhoneNumber, user.Balance, user.PasswordHash, user.Role, time.Now().UTC(), domain.ActorFromContext(ctx), user.UserID, user.Version))
+		logger.Trace().Msg("Query ran")
+
+		if errScan != nil {
+			if errScan == sql.ErrNoRows {
+				return missingOrConflict(ctx, trx, "user", "SELECT COUNT(*) FROM users WHERE user_id=?1 AND deleted_at IS NULL", user.UserID, user.Version)
+			}
+			return errScan
+		}
+
+		if err = trx.Commit(); err != nil {
+			return err
+		}
+
+		*user = updatedUser
+		logger.Info().Msg("User updated")
+		return nil
+	}
+}
+
+func (repo *UserRepository) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	ctx, end := observe(ctx, "user", "delete")
+	defer end()
+	repo.mtx.Lock()
+	defer repo.mtx.Unlock()
+
+	logger.Trace().Msg("Inside user repository delete")
+	logger.Debug().Msg(fmt.Sprintf("User repo delete received id with value %d", id))
+
+	select {
+	case <- ctx.Done():
+		logger.Error().Msg(fmt.Sprintf("Error when trying to delete user because of timeout with message: %s", ctx.Err()))
+		return domain.Timeout(ctx.Err())
+	default:
+		logger.Trace().Msg("Attempting to soft delete user")
+
+		res, err := repo.db.ExecContext(ctx, `UPDATE users SET deleted_at=?1, updated_at=?1, updated_by=?2, version=version+1 WHERE user_id=?3 AND deleted_at IS NULL`, time.Now().UTC(), domain.ActorFromContext(ctx), id)
+		logger.Trace().Msg("Query ran")
+
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			logger.Error().Msg(fmt.Sprintf("User with ID %d not found", id))
+			return &domain.NotFoundError{Entity: "user", ID: id}
+		}
+
+		logger.Info().Msg("User deleted")
+		return nil
+	}
+}
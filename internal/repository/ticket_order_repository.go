@@ -9,6 +9,10 @@ type TicketOrderRepositoryInterface interface {
 	TicketOrderSave
 	TicketOrderFindById
 	TicketOrderGetAll
+	TicketOrderGetByOrganizerId
+	TicketOrderUpdate
+	TicketOrderDelete
+	TicketOrderCount
 }
 
 type TicketOrderSave interface {
@@ -21,4 +25,22 @@ type TicketOrderFindById interface {
 
 type TicketOrderGetAll interface {
 	GetAll(context context.Context) ([]domain.TicketOrder, error)
+}
+
+type TicketOrderGetByOrganizerId interface {
+	GetByOrganizerID(context context.Context, organizerID int) ([]domain.TicketOrder, error)
+}
+
+type TicketOrderUpdate interface {
+	Update(context context.Context, ticketOrder *domain.TicketOrder) (error)
+}
+
+type TicketOrderDelete interface {
+	Delete(context context.Context, id int) (error)
+}
+
+type TicketOrderCount interface {
+	CountByEventID(context context.Context, eventID int) (int, error)
+	CountByTicketID(context context.Context, ticketID int) (int, error)
+	CountByUserID(context context.Context, userID int) (int, error)
 }
\ No newline at end of file
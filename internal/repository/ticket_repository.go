@@ -10,7 +10,9 @@ type TicketRepositoryInterface interface {
 	TicketFindById
 	TicketGetAll
 	TicketDeduct
-	// TicketRestore
+	TicketRestore
+	TicketUpdate
+	TicketDelete
 }
 
 type TicketSave interface {
@@ -30,5 +32,13 @@ type TicketDeduct interface {
 }
 
 type TicketRestore interface {
-	Restore(context context.Context, id int, amount int)
+	Restore(context context.Context, id int, amount int) (domain.Ticket, error)
+}
+
+type TicketUpdate interface {
+	Update(context context.Context, ticket *domain.Ticket) (error)
+}
+
+type TicketDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
@@ -8,8 +8,12 @@ import (
 type UserRepositoryInterface interface {
 	UserSave
 	UserFindById
+	UserFindByEmail
 	UserGetAll
 	UserBalanceReduce
+	UserBalanceAdd
+	UserUpdate
+	UserDelete
 }
 
 type UserSave interface {
@@ -20,10 +24,26 @@ type UserFindById interface {
 	FindByID(context context.Context, id int) (domain.User, error)
 }
 
+type UserFindByEmail interface {
+	FindByEmail(context context.Context, email string) (domain.User, error)
+}
+
 type UserGetAll interface {
 	GetAll(context context.Context) ([]domain.User, error)
 }
 
 type UserBalanceReduce interface {
 	ReduceBalance(context context.Context, id int, amount float64) (domain.User, error)
+}
+
+type UserBalanceAdd interface {
+	AddBalance(context context.Context, id int, amount float64) (domain.User, error)
+}
+
+type UserUpdate interface {
+	Update(context context.Context, user *domain.User) (error)
+}
+
+type UserDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
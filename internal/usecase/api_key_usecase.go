@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"ticket_goroutine/internal/domain"
+)
+
+type APIKeyUseCaseInterface interface {
+	APIKeySave
+	APIKeyFindById
+	APIKeyGetAll
+	APIKeyDelete
+	APIKeyGetUsage
+	APIKeyVerify
+	APIKeyRecordUsage
+}
+
+type APIKeySave interface {
+	Save(context context.Context, key domain.APIKey) (domain.APIKey, error)
+}
+
+type APIKeyFindById interface {
+	FindById(context context.Context, id int) (domain.APIKey, error)
+}
+
+type APIKeyGetAll interface {
+	GetAll(context context.Context) ([]domain.APIKey, error)
+}
+
+type APIKeyDelete interface {
+	Delete(context context.Context, id int) (error)
+}
+
+type APIKeyGetUsage interface {
+	GetUsage(context context.Context, id int) ([]domain.APIKeyUsage, error)
+}
+
+type APIKeyVerify interface {
+	Verify(context context.Context, key string) (domain.Caller, error)
+}
+
+type APIKeyRecordUsage interface {
+	RecordUsage(context context.Context, usage domain.APIKeyUsage) (error)
+}
@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"context"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+)
+
+type AuthUseCaseInterface interface {
+	AuthLogin
+	AuthRefresh
+}
+
+type AuthLogin interface {
+	Login(context context.Context, credentials domain.Credentials) (dto.TokenResponse, error)
+}
+
+type AuthRefresh interface {
+	Refresh(context context.Context, refreshToken string) (dto.TokenResponse, error)
+}
@@ -9,6 +9,10 @@ type EventUseCaseInterface interface {
 	EventSave
 	EventFindById
 	EventGetAll
+	EventGetByOrganizer
+	EventUpdate
+	EventPatch
+	EventDelete
 }
 
 type EventSave interface {
@@ -21,4 +25,20 @@ type EventFindById interface {
 
 type EventGetAll interface {
 	GetAll(context context.Context) ([]domain.Event, error)
+}
+
+type EventGetByOrganizer interface {
+	GetByOrganizer(context context.Context, organizerID int) ([]domain.Event, error)
+}
+
+type EventUpdate interface {
+	Update(context context.Context, id int, event domain.Event) (domain.Event, error)
+}
+
+type EventPatch interface {
+	Patch(context context.Context, id int, patch []byte) (domain.Event, error)
+}
+
+type EventDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
@@ -0,0 +1,153 @@
+package impl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/repository"
+	"ticket_goroutine/internal/usecase"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// apiKeyMarker starts every key so they are easy to tell apart from tokens and to find in leaked code
+const apiKeyMarker = "tgk"
+
+type APIKeyUseCase struct {
+	repo     repository.APIKeyRepositoryInterface
+	repoUser repository.UserRepositoryInterface
+}
+
+func NewAPIKeyUseCase(repo repository.APIKeyRepositoryInterface, repoUser repository.UserRepositoryInterface) (usecase.APIKeyUseCaseInterface) {
+	return APIKeyUseCase{
+		repo:     repo,
+		repoUser: repoUser,
+	}
+}
+
+// errBadAPIKey doesn't tell whether the key is unknown, revoked or wrong
+var errBadAPIKey = fmt.Errorf("invalid api key: %w", domain.ErrUnauthorized)
+
+func (uc APIKeyUseCase) Save(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering api key usecase save")
+
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return domain.APIKey{}, &domain.ValidationError{Message: "api key would already be expired", Fields: map[string]string{"ExpiresAt": "ExpiresAt must be in the future"}}
+	}
+
+	logger.Info().Msg("Attempting to call user repo to check the key's user exist")
+	if _, err := uc.repoUser.FindByID(ctx, key.UserID); err != nil {
+		return domain.APIKey{}, err
+	}
+
+	prefix, err := randomString(6, hex.EncodeToString)
+
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	key.Prefix = prefix
+	key.Key = fmt.Sprintf("%s_%s_%s", apiKeyMarker, prefix, secret)
+	key.KeyHash = hashAPIKey(key.Key)
+
+	logger.Info().Msg("Attempting to call api key repo save")
+	err = uc.repo.Save(ctx, &key)
+	return key, err
+}
+
+func (uc APIKeyUseCase) FindById(ctx context.Context, id int) (domain.APIKey, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering api key usecase find by id")
+	return uc.repo.FindByID(ctx, id)
+}
+
+func (uc APIKeyUseCase) GetAll(ctx context.Context) ([]domain.APIKey, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering api key usecase get all")
+	return uc.repo.GetAll(ctx)
+}
+
+func (uc APIKeyUseCase) Delete(ctx context.Context, id int) (error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering api key usecase delete")
+	return uc.repo.Delete(ctx, id)
+}
+
+func (uc APIKeyUseCase) GetUsage(ctx context.Context, id int) ([]domain.APIKeyUsage, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering api key usecase get usage")
+	logger.Info().Msg("Attempting to call api key repo to check if api key exist")
+
+	// usage of revoked keys stays readable for auditing
+	if _, err := uc.repo.FindByID(domain.WithIncludeDeleted(ctx), id); err != nil {
+		return []domain.APIKeyUsage{}, err
+	}
+
+	return uc.repo.GetUsage(ctx, id)
+}
+
+// Verify resolves a presented key into the caller it acts for
+func (uc APIKeyUseCase) Verify(ctx context.Context, key string) (domain.Caller, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering api key usecase verify")
+	marker, rest, _ := strings.Cut(key, "_")
+	prefix, _, found := strings.Cut(rest, "_")
+
+	if marker != apiKeyMarker || !found {
+		return domain.Caller{}, errBadAPIKey
+	}
+
+	foundKey, err := uc.repo.FindByPrefix(ctx, prefix)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Caller{}, errBadAPIKey
+		}
+		return domain.Caller{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(foundKey.KeyHash)) != 1 {
+		logger.Warn().Msg(fmt.Sprintf("Wrong secret for api key %d", foundKey.KeyID))
+		return domain.Caller{}, errBadAPIKey
+	}
+
+	if foundKey.ExpiresAt != nil && !foundKey.ExpiresAt.After(time.Now()) {
+		logger.Warn().Msg(fmt.Sprintf("API key %d expired at %s", foundKey.KeyID, foundKey.ExpiresAt))
+		return domain.Caller{}, fmt.Errorf("api key expired: %w", domain.ErrUnauthorized)
+	}
+
+	return domain.Caller{UserID: foundKey.UserID, APIKeyID: foundKey.KeyID, Scopes: foundKey.Scopes}, nil
+}
+
+func (uc APIKeyUseCase) RecordUsage(ctx context.Context, usage domain.APIKeyUsage) (error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering api key usecase record usage")
+	return uc.repo.RecordUsage(ctx, &usage)
+}
+
+// hashAPIKey needs no salt or stretching, the keys are long random strings rather than passwords
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, size)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return encode(buf), nil
+}
@@ -0,0 +1,98 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/provider/auth"
+	"ticket_goroutine/internal/repository"
+	"ticket_goroutine/internal/usecase"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthUseCase struct {
+	repoUser repository.UserRepositoryInterface
+	tokens   *auth.TokenIssuer
+}
+
+func NewAuthUseCase(repoUser repository.UserRepositoryInterface, tokens *auth.TokenIssuer) (usecase.AuthUseCaseInterface) {
+	return AuthUseCase{
+		repoUser: repoUser,
+		tokens:   tokens,
+	}
+}
+
+// errBadCredentials doesn't tell whether the email or the password was wrong
+var errBadCredentials = fmt.Errorf("invalid email or password: %w", domain.ErrUnauthorized)
+
+func (uc AuthUseCase) Login(ctx context.Context, credentials domain.Credentials) (dto.TokenResponse, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering auth usecase login")
+	logger.Info().Msg("Attempting to call user repo to find the user by email")
+	foundUser, err := uc.repoUser.FindByEmail(ctx, credentials.Email)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return dto.TokenResponse{}, errBadCredentials
+		}
+		return dto.TokenResponse{}, err
+	}
+
+	// users created before credentials existed have no hash and cannot log in
+	if foundUser.PasswordHash == "" {
+		logger.Warn().Msg(fmt.Sprintf("User with ID %d has no password set", foundUser.UserID))
+		return dto.TokenResponse{}, errBadCredentials
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(foundUser.PasswordHash), []byte(credentials.Password)) != nil {
+		logger.Warn().Msg(fmt.Sprintf("Wrong password for user with ID %d", foundUser.UserID))
+		return dto.TokenResponse{}, errBadCredentials
+	}
+
+	return uc.issue(ctx, foundUser)
+}
+
+func (uc AuthUseCase) Refresh(ctx context.Context, refreshToken string) (dto.TokenResponse, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering auth usecase refresh")
+	identity, err := uc.tokens.Parse(refreshToken, auth.RefreshToken)
+
+	if err != nil {
+		logger.Warn().Msg(fmt.Sprintf("Refresh token rejected: %s", err))
+		return dto.TokenResponse{}, fmt.Errorf("%w: %w", domain.ErrUnauthorized, err)
+	}
+
+	// the user is read again so a changed role takes effect with the new tokens
+	logger.Info().Msg("Attempting to call user repo to check the user still exist")
+	foundUser, err := uc.repoUser.FindByID(ctx, identity.UserID)
+
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return dto.TokenResponse{}, fmt.Errorf("user of the refresh token no longer exist: %w", domain.ErrUnauthorized)
+		}
+		return dto.TokenResponse{}, err
+	}
+
+	return uc.issue(ctx, foundUser)
+}
+
+func (uc AuthUseCase) issue(ctx context.Context, user domain.User) (dto.TokenResponse, error) {
+	pair, err := uc.tokens.Issue(auth.Identity{UserID: user.UserID, Role: string(user.Role)})
+
+	if err != nil {
+		return dto.TokenResponse{}, err
+	}
+
+	zerolog.Ctx(ctx).Info().Msg(fmt.Sprintf("Tokens issued for user with ID %d as %s", user.UserID, user.Role))
+	return dto.TokenResponse{
+		AccessToken:      pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(pair.AccessExpiresIn.Seconds()),
+		RefreshExpiresIn: int(pair.RefreshExpiresIn.Seconds()),
+	}, nil
+}
@@ -2,36 +2,128 @@ package impl
 
 import (
 	"context"
+	"fmt"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/repository"
 	"ticket_goroutine/internal/usecase"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type EventUseCase struct {
 	repo repository.EventRepositoryInterface
+	repoTicketOrder repository.TicketOrderRepositoryInterface
 }
 
-func NewEventUseCase(repo repository.EventRepositoryInterface) (usecase.EventUseCaseInterface) {
+func NewEventUseCase(repo repository.EventRepositoryInterface, repoTicketOrder repository.TicketOrderRepositoryInterface) (usecase.EventUseCaseInterface) {
 	return EventUseCase{
 		repo: repo,
+		repoTicketOrder: repoTicketOrder,
 	}
 }
 
 func (uc EventUseCase) Save(ctx context.Context, event domain.Event) (domain.Event, error) {
-	log.Trace().Msg("Entering event usecase save")
+	zerolog.Ctx(ctx).Trace().Msg("Entering event usecase save")
+
+	// organizers always own the events they create, admins may create one for any organizer
+	if caller, ok := domain.CallerFromContext(ctx); ok && caller.Is(domain.RoleOrganizer) {
+		event.OrganizerID = caller.UserID
+	}
+
 	err := uc.repo.Save(ctx, &event)
 
 	return event, err
 }
 
 func (uc EventUseCase) FindById(ctx context.Context, id int) (domain.Event, error) {
-	log.Trace().Msg("Entering event usecase find by id")
+	zerolog.Ctx(ctx).Trace().Msg("Entering event usecase find by id")
 	return uc.repo.FindByID(ctx, id)
 }
 
 func (uc EventUseCase) GetAll(ctx context.Context) ([]domain.Event, error) {
-	log.Trace().Msg("Entering event usecase get all")
+	zerolog.Ctx(ctx).Trace().Msg("Entering event usecase get all")
 	return uc.repo.GetAll(ctx)
+}
+
+func (uc EventUseCase) GetByOrganizer(ctx context.Context, organizerID int) ([]domain.Event, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering event usecase get by organizer")
+	return uc.repo.GetByOrganizerID(ctx, organizerID)
+}
+
+func (uc EventUseCase) Update(ctx context.Context, id int, event domain.Event) (domain.Event, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering event usecase update")
+	event.EventID = id
+
+	logger.Info().Msg("Attempting to call event repo to check if event exist")
+	foundEvent, err := uc.repo.FindByID(ctx, id)
+
+	if err != nil {
+		return domain.Event{}, err
+	}
+
+	if err := checkEventOwner(ctx, foundEvent); err != nil {
+		return domain.Event{}, err
+	}
+
+	if event.Version == 0 {
+		logger.Info().Msg("No version given, updating against the current version")
+		event.Version = foundEvent.Version
+	}
+
+	if event.OrganizerID == 0 {
+		event.OrganizerID = foundEvent.OrganizerID
+	}
+
+	if event.OrganizerID != foundEvent.OrganizerID && !isAdmin(ctx) {
+		return domain.Event{}, fmt.Errorf("only an admin can hand an event to another organizer: %w", domain.ErrForbidden)
+	}
+
+	err = uc.repo.Update(ctx, &event)
+	return event, err
+}
+
+func (uc EventUseCase) Patch(ctx context.Context, id int, patch []byte) (domain.Event, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering event usecase patch")
+	foundEvent, err := uc.repo.FindByID(ctx, id)
+
+	if err != nil {
+		return domain.Event{}, err
+	}
+
+	patchedEvent, err := applyPatch(foundEvent, patch)
+
+	if err != nil {
+		return domain.Event{}, err
+	}
+
+	return uc.Update(ctx, id, patchedEvent)
+}
+
+func (uc EventUseCase) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering event usecase delete")
+	logger.Info().Msg("Attempting to call event repo to check if event exist")
+	foundEvent, err := uc.repo.FindByID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := checkEventOwner(ctx, foundEvent); err != nil {
+		return err
+	}
+
+	logger.Info().Msg("Attempting to check if event has paid orders")
+	count, err := uc.repoTicketOrder.CountByEventID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("event with ID %d %w and cannot be deleted", id, domain.ErrHasPaidOrders)
+	}
+
+	return uc.repo.Delete(ctx, id)
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"ticket_goroutine/internal/domain"
+)
+
+// checkEventOwner lets organizers manage only the events they own, and the tickets of those events.
+// Admins and requests without a caller may manage every event.
+func checkEventOwner(ctx context.Context, event domain.Event) error {
+	caller, ok := domain.CallerFromContext(ctx)
+
+	if !ok || caller.Is(domain.RoleAdmin) || event.OrganizerID == caller.UserID {
+		return nil
+	}
+
+	return fmt.Errorf("event with ID %d belongs to another organizer: %w", event.EventID, domain.ErrForbidden)
+}
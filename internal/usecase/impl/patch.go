@@ -0,0 +1,38 @@
+package impl
+
+import (
+	"bytes"
+	"encoding/json"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/utils"
+)
+
+// applyPatch merges a JSON merge patch into current and validates the outcome like a fresh request body
+func applyPatch[T any](current T, patch []byte) (T, error) {
+	var patched T
+
+	original, err := json.Marshal(current)
+
+	if err != nil {
+		return patched, err
+	}
+
+	merged, err := utils.MergePatch(original, patch)
+
+	if err != nil {
+		return patched, &domain.ValidationError{Message: err.Error()}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&patched); err != nil {
+		return patched, &domain.ValidationError{Message: err.Error()}
+	}
+
+	if err := utils.ValidateStruct(&patched); err != nil {
+		return patched, err
+	}
+
+	return patched, nil
+}
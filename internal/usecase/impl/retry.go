@@ -0,0 +1,33 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticket_goroutine/internal/domain"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxConflictRetries bounds how many times an update that lost an optimistic concurrency race is retried
+const maxConflictRetries = 3
+
+// retryOnConflict runs operation again, after a short growing pause, whenever it fails with a version conflict
+func retryOnConflict[T any](ctx context.Context, name string, operation func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := operation()
+
+		if err == nil || !errors.Is(err, domain.ErrVersionConflict) || attempt > maxConflictRetries {
+			return result, err
+		}
+
+		zerolog.Ctx(ctx).Warn().Msg(fmt.Sprintf("%s hit a version conflict, retry %d of %d", name, attempt, maxConflictRetries))
+
+		select {
+		case <- ctx.Done():
+			return result, domain.Timeout(ctx.Err())
+		case <- time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
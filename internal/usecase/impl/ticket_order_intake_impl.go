@@ -0,0 +1,251 @@
+package impl
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/provider/metrics"
+	"ticket_goroutine/internal/provider/tracing"
+	"ticket_goroutine/internal/provider/worker"
+	"ticket_goroutine/internal/usecase"
+	"ticket_goroutine/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// orderRequest is one order waiting for or being placed by a worker
+type orderRequest struct {
+	userID     int
+	response   dto.OrderRequestResponse
+	done       chan struct{}
+	queuedAt   time.Time
+	finishedAt time.Time
+}
+
+// TicketOrderIntake places orders on a worker pool, so a burst of buyers queues up instead of each
+// taking a goroutine and a database connection. Orders still queued after queueWait are failed instead of
+// placed, the buyer gave up on them by then. Finished requests are kept for keepFor to be polled.
+type TicketOrderIntake struct {
+	mtx        sync.Mutex
+	pool       *worker.Pool
+	orders     usecase.TicketOrderSave
+	requests   map[string]*orderRequest
+	queueWait  time.Duration
+	jobTimeout time.Duration
+	keepFor    time.Duration
+	lastSweep  time.Time
+}
+
+func NewTicketOrderIntake(pool *worker.Pool, orders usecase.TicketOrderSave, queueWait time.Duration, jobTimeout time.Duration, keepFor time.Duration) (usecase.TicketOrderIntakeInterface) {
+	return &TicketOrderIntake{
+		pool:       pool,
+		orders:     orders,
+		requests:   map[string]*orderRequest{},
+		queueWait:  queueWait,
+		jobTimeout: jobTimeout,
+		keepFor:    keepFor,
+	}
+}
+
+// Submit queues ticketOrder, which is placed with ctx, answering domain.ErrUnavailable when the queue is full
+func (in *TicketOrderIntake) Submit(ctx context.Context, ticketOrder domain.TicketOrder) (dto.OrderRequestResponse, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order intake submit")
+	requestID, err := newRequestID()
+
+	if err != nil {
+		return dto.OrderRequestResponse{}, err
+	}
+
+	request := &orderRequest{
+		userID:   ticketOrder.UserID,
+		response: dto.OrderRequestResponse{RequestID: requestID, Status: dto.OrderRequestQueued},
+		done:     make(chan struct{}),
+		queuedAt: time.Now(),
+	}
+
+	in.mtx.Lock()
+	in.sweep(time.Now())
+	in.requests[requestID] = request
+	in.mtx.Unlock()
+
+	err = in.pool.Submit(func() {
+		in.place(ctx, request, ticketOrder)
+	})
+
+	if err != nil {
+		in.mtx.Lock()
+		delete(in.requests, requestID)
+		in.mtx.Unlock()
+
+		if errors.Is(err, worker.ErrQueueFull) || errors.Is(err, worker.ErrStopped) {
+			metrics.OrderFailed("queue_full")
+			return dto.OrderRequestResponse{}, fmt.Errorf("not taking more orders right now, %w: %w", err, domain.ErrUnavailable)
+		}
+		return dto.OrderRequestResponse{}, err
+	}
+
+	logger.Info().Msg(fmt.Sprintf("Order request %s queued for user with ID %d", requestID, ticketOrder.UserID))
+	return request.response, nil
+}
+
+func (in *TicketOrderIntake) place(ctx context.Context, request *orderRequest, ticketOrder domain.TicketOrder) {
+	logger := zerolog.Ctx(ctx)
+	ctx, span := tracing.Start(ctx, "TicketOrderIntake.place", attribute.String("order_request_id", request.response.RequestID))
+	defer span.End()
+
+	var savedTicketOrder dto.TicketOrderResponseSave
+	var err error
+
+	if waited := time.Since(request.queuedAt); waited > in.queueWait {
+		err = domain.Timeout(fmt.Errorf("the order waited %s for a worker, more than %s", waited, in.queueWait))
+	} else {
+		in.mtx.Lock()
+		request.response.Status = dto.OrderRequestProcessing
+		in.mtx.Unlock()
+
+		ctx, cancel := context.WithTimeout(ctx, in.jobTimeout)
+		defer cancel()
+
+		savedTicketOrder, err = in.save(ctx, ticketOrder)
+	}
+
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+
+	if err != nil {
+		logger.Warn().Msg(fmt.Sprintf("Order request %s failed: %s", request.response.RequestID, err))
+		request.response.Status = dto.OrderRequestFailed
+		request.response.Failure = err
+		metrics.OrderFailed(orderFailureReason(err))
+		tracing.Fail(span, err)
+	} else {
+		logger.Info().Msg(fmt.Sprintf("Order request %s placed order with ID %d", request.response.RequestID, savedTicketOrder.OrderID))
+		request.response.Status = dto.OrderRequestCompleted
+		request.response.Order = &savedTicketOrder
+		metrics.OrderPlaced(savedTicketOrder.TicketDetails.EventDetails.EventID, savedTicketOrder.Amount)
+	}
+
+	request.finishedAt = time.Now()
+	close(request.done)
+}
+
+// save places the order, a panic fails the request instead of leaving it processing forever
+func (in *TicketOrderIntake) save(ctx context.Context, ticketOrder domain.TicketOrder) (savedTicketOrder dto.TicketOrderResponseSave, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicked := utils.Recovered(recovered)
+			zerolog.Ctx(ctx).Error().Str("stack", string(panicked.Stack)).Msg(fmt.Sprintf("Placing an order panicked: %s", panicked))
+			err = panicked
+		}
+	}()
+
+	return in.orders.Save(ctx, ticketOrder)
+}
+
+// orderFailureReason names the kind of error an order failed with, for the failed orders metric
+func orderFailureReason(err error) string {
+	var panicked *utils.PanicError
+
+	switch {
+	case errors.As(err, &panicked):
+		return "panic"
+	case errors.Is(err, domain.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.Is(err, domain.ErrValidation):
+		return "validation"
+	case errors.Is(err, domain.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, domain.ErrConflict):
+		return "conflict"
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return "insufficient_stock"
+	case errors.Is(err, domain.ErrInsufficientBalance):
+		return "insufficient_balance"
+	case errors.Is(err, domain.ErrForbidden):
+		return "forbidden"
+	default:
+		return "internal"
+	}
+}
+
+// Wait blocks until the request finished, or ctx is done
+func (in *TicketOrderIntake) Wait(ctx context.Context, requestID string) (dto.OrderRequestResponse, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering ticket order intake wait")
+	request, err := in.find(ctx, requestID)
+
+	if err != nil {
+		return dto.OrderRequestResponse{}, err
+	}
+
+	select {
+	case <- ctx.Done():
+		return dto.OrderRequestResponse{}, domain.Timeout(ctx.Err())
+	case <- request.done:
+	}
+
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	return request.response, nil
+}
+
+// Status reports how far the request got, without waiting
+func (in *TicketOrderIntake) Status(ctx context.Context, requestID string) (dto.OrderRequestResponse, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering ticket order intake status")
+	request, err := in.find(ctx, requestID)
+
+	if err != nil {
+		return dto.OrderRequestResponse{}, err
+	}
+
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	return request.response, nil
+}
+
+// find returns the request when the caller placed it or is an admin, others see it as not found
+func (in *TicketOrderIntake) find(ctx context.Context, requestID string) (*orderRequest, error) {
+	in.mtx.Lock()
+	request, ok := in.requests[requestID]
+	in.mtx.Unlock()
+
+	caller, _ := domain.CallerFromContext(ctx)
+
+	if !ok || (request.userID != caller.UserID && !isAdmin(ctx)) {
+		return nil, fmt.Errorf("no order request found with ID %s: %w", requestID, domain.ErrNotFound)
+	}
+
+	return request, nil
+}
+
+// sweep drops the requests finished more than keepFor ago, the lock must be held
+func (in *TicketOrderIntake) sweep(now time.Time) {
+	if now.Sub(in.lastSweep) < time.Minute {
+		return
+	}
+
+	for requestID, request := range in.requests {
+		if !request.finishedAt.IsZero() && now.Sub(request.finishedAt) > in.keepFor {
+			delete(in.requests, requestID)
+		}
+	}
+
+	in.lastSweep = now
+}
+
+func newRequestID() (string, error) {
+	id := make([]byte, 12)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("generating order request ID failed: %w", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
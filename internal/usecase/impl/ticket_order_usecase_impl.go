@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/provider/tracing"
 	"ticket_goroutine/internal/repository"
 	"ticket_goroutine/internal/usecase"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TicketOrderUseCase struct {
@@ -31,23 +33,26 @@ func NewTicketOrderUseCase(repoTicketOrder repository.TicketOrderRepositoryInter
 }
 
 func (uc TicketOrderUseCase) Save(ctx context.Context, ticketOrder domain.TicketOrder) (dto.TicketOrderResponseSave, error) {
-	log.Trace().Msg("Entering ticket order usecase save")
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase save")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.Save", attribute.Int("ticket_id", ticketOrder.TicketID), attribute.Int("amount", ticketOrder.Amount))
+	defer span.End()
 
-	log.Info().Msg("Attempting to check ticket if exist")
+	logger.Info().Msg("Attempting to check ticket if exist")
 	foundTicket, errTicket := uc.useCaseTicket.FindById(ctx, ticketOrder.TicketID)
 
 	if errTicket != nil {
 		return dto.TicketOrderResponseSave{}, errTicket
 	}
 
-	log.Info().Msg("Attempting to check event if exist")
+	logger.Info().Msg("Attempting to check event if exist")
 	foundEvent, errEvent := uc.useCaseEvent.FindById(ctx, foundTicket.EventDetails.EventID)
 
 	if errEvent != nil {
 		return dto.TicketOrderResponseSave{}, errEvent
 	}
 
-	log.Info().Msg("Attempting to check user if exist")
+	logger.Info().Msg("Attempting to check user if exist")
 	foundUser, errUser := uc.useCaseUser.FindById(ctx, ticketOrder.UserID)
 
 	if errUser != nil {
@@ -55,13 +60,13 @@ func (uc TicketOrderUseCase) Save(ctx context.Context, ticketOrder domain.Ticket
 	}
 
 	if foundTicket.Stock < ticketOrder.Amount {
-		return dto.TicketOrderResponseSave{}, fmt.Errorf("not enough stock")
+		return dto.TicketOrderResponseSave{}, domain.ErrInsufficientStock
 	}
 
 	var totalPrice float64 = float64(ticketOrder.Amount) * foundTicket.Price
 
 	if foundUser.Balance < totalPrice {
-		return dto.TicketOrderResponseSave{}, fmt.Errorf("not enough balance")
+		return dto.TicketOrderResponseSave{}, domain.ErrInsufficientBalance
 	}
 
 	deductedTicket, errDeduct := uc.useCaseTicket.Deduct(ctx, foundTicket.TicketID, ticketOrder.Amount)
@@ -73,10 +78,12 @@ func (uc TicketOrderUseCase) Save(ctx context.Context, ticketOrder domain.Ticket
 	reducedUserBalance, errBalance := uc.useCaseUser.ReduceBalance(ctx, foundUser.UserID, totalPrice)
 	
 	if errBalance != nil {
+		logger.Info().Msg("Charging the user failed, giving the deducted tickets back")
+		uc.giveBack(ctx, foundTicket.TicketID, ticketOrder.Amount, foundUser.UserID, 0)
 		return dto.TicketOrderResponseSave{}, errBalance
 	}
 
-	log.Info().Msg("Attempting to call ticket order repo save")
+	logger.Info().Msg("Attempting to call ticket order repo save")
 	ticketSave := domain.TicketOrder {
 		OrderID: ticketOrder.OrderID,
 		TicketID: ticketOrder.TicketID,
@@ -87,6 +94,8 @@ func (uc TicketOrderUseCase) Save(ctx context.Context, ticketOrder domain.Ticket
 	errSaved := uc.repoTicketOrder.Save(ctx, &ticketSave)
 
 	if errSaved != nil {
+		logger.Info().Msg("Saving the order failed, giving the tickets back and refunding the user")
+		uc.giveBack(ctx, foundTicket.TicketID, ticketOrder.Amount, foundUser.UserID, totalPrice)
 		return dto.TicketOrderResponseSave{}, errSaved
 	}
 
@@ -117,30 +126,36 @@ func (uc TicketOrderUseCase) Save(ctx context.Context, ticketOrder domain.Ticket
 }
 
 func (uc TicketOrderUseCase) FindById(ctx context.Context, id int) (dto.TicketOrderResponse, error) {
-	log.Trace().Msg("Entering ticket order usecase find by id")
-	log.Info().Msg("Attempting to call ticket order repo to check if ticket order exist")
-	foundTicketOrder, errTicketOrder := uc.repoTicketOrder.FindByID(ctx, id)
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase find by id")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.FindById", attribute.Int("order_id", id))
+	defer span.End()
+	logger.Info().Msg("Attempting to call ticket order repo to check if ticket order exist")
+	foundTicketOrder, errTicketOrder := uc.findOwned(ctx, id)
 
 	if errTicketOrder != nil {
 		return dto.TicketOrderResponse{}, errTicketOrder
 	}
-	
-	log.Info().Msg("Attempting to call ticket use case to check if ticket exist")
-	foundTicket, errTicket := uc.useCaseTicket.FindById(ctx, foundTicketOrder.TicketID)
+
+	// an order keeps showing the ticket, event and user it was placed with even after they are deleted
+	detailsCtx := domain.WithIncludeDeleted(ctx)
+
+	logger.Info().Msg("Attempting to call ticket use case to check if ticket exist")
+	foundTicket, errTicket := uc.useCaseTicket.FindById(detailsCtx, foundTicketOrder.TicketID)
 
 	if errTicket != nil {
 		return dto.TicketOrderResponse{}, errTicket
 	}
 
-	log.Info().Msg("Attempting to call event use case to check if event exist")
-	foundEvent, errEvent := uc.useCaseEvent.FindById(ctx, foundTicket.EventDetails.EventID)
+	logger.Info().Msg("Attempting to call event use case to check if event exist")
+	foundEvent, errEvent := uc.useCaseEvent.FindById(detailsCtx, foundTicket.EventDetails.EventID)
 
 	if errEvent != nil {
 		return dto.TicketOrderResponse{}, errEvent
 	}
 
-	log.Info().Msg("Attempting to call user use case to check if user exist")
-	foundUser, errUser := uc.useCaseUser.FindById(ctx, foundTicketOrder.UserID)
+	logger.Info().Msg("Attempting to call user use case to check if user exist")
+	foundUser, errUser := uc.useCaseUser.FindById(detailsCtx, foundTicketOrder.UserID)
 
 	if errUser != nil {
 		return dto.TicketOrderResponse{}, errUser
@@ -171,33 +186,67 @@ func (uc TicketOrderUseCase) FindById(ctx context.Context, id int) (dto.TicketOr
 }
 
 func (uc TicketOrderUseCase) GetAll(ctx context.Context) ([]dto.TicketOrderResponse, error) {
-	log.Trace().Msg("Entering ticket order usecase get all")
-	log.Info().Msg("Attempting to call fetch all ticket")
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase get all")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.GetAll")
+	defer span.End()
+	logger.Info().Msg("Attempting to call fetch all ticket")
 	listOfTicketOrder, err := uc.repoTicketOrder.GetAll(ctx)
 
 	if err != nil {
 		return []dto.TicketOrderResponse{}, err
 	}
 
+	var listOfVisible []domain.TicketOrder
+
+	for _, v := range listOfTicketOrder {
+		if canSeeOrder(ctx, v) {
+			listOfVisible = append(listOfVisible, v)
+		}
+	}
+
+	return uc.withDetails(ctx, listOfVisible)
+}
+
+// GetByOrganizer lists the sales of the organizer's events
+func (uc TicketOrderUseCase) GetByOrganizer(ctx context.Context, organizerID int) ([]dto.TicketOrderResponse, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase get by organizer")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.GetByOrganizer", attribute.Int("organizer_id", organizerID))
+	defer span.End()
+	logger.Info().Msg("Attempting to call ticket order repo to fetch the organizer's sales")
+	listOfTicketOrder, err := uc.repoTicketOrder.GetByOrganizerID(ctx, organizerID)
+
+	if err != nil {
+		return []dto.TicketOrderResponse{}, err
+	}
+
+	return uc.withDetails(ctx, listOfTicketOrder)
+}
+
+// withDetails adds the ticket, event and user of every order to the response
+func (uc TicketOrderUseCase) withDetails(ctx context.Context, listOfTicketOrder []domain.TicketOrder) ([]dto.TicketOrderResponse, error) {
+	logger := zerolog.Ctx(ctx)
 	var listOfTicketOrderResponse []dto.TicketOrderResponse
+	detailsCtx := domain.WithIncludeDeleted(ctx)
 
 	for _, v := range listOfTicketOrder {
-		log.Info().Msg("Attempting to call ticket use case to find ticket by id")
-		foundTicket, errTicket := uc.useCaseTicket.FindById(ctx, v.TicketID)
+		logger.Info().Msg("Attempting to call ticket use case to find ticket by id")
+		foundTicket, errTicket := uc.useCaseTicket.FindById(detailsCtx, v.TicketID)
 
 		if errTicket != nil {
 			return []dto.TicketOrderResponse{}, errTicket
 		}
 
-		log.Info().Msg("Attempting to call event use case to find event by id")
-		foundEvent, errEvent := uc.useCaseEvent.FindById(ctx, foundTicket.EventDetails.EventID)
+		logger.Info().Msg("Attempting to call event use case to find event by id")
+		foundEvent, errEvent := uc.useCaseEvent.FindById(detailsCtx, foundTicket.EventDetails.EventID)
 
 		if errEvent != nil {
 			return []dto.TicketOrderResponse{}, errEvent
 		}
 
-		log.Info().Msg("Attempting to call user use case to find user by id")
-		foundUser, errUser := uc.useCaseUser.FindById(ctx, v.UserID)
+		logger.Info().Msg("Attempting to call user use case to find user by id")
+		foundUser, errUser := uc.useCaseUser.FindById(detailsCtx, v.UserID)
 
 		if errUser != nil {
 			return []dto.TicketOrderResponse{}, errUser
@@ -228,4 +277,219 @@ func (uc TicketOrderUseCase) GetAll(ctx context.Context) ([]dto.TicketOrderRespo
 		listOfTicketOrderResponse = append(listOfTicketOrderResponse, ticketOrderResponse)
 	}
 	return listOfTicketOrderResponse, nil
-}
\ No newline at end of file
+}
+
+func (uc TicketOrderUseCase) Update(ctx context.Context, id int, ticketOrder domain.TicketOrder) (dto.TicketOrderResponseSave, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase update")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.Update", attribute.Int("order_id", id))
+	defer span.End()
+	logger.Info().Msg("Attempting to call ticket order repo to check if ticket order exist")
+	foundTicketOrder, errTicketOrder := uc.findOwned(ctx, id)
+
+	if errTicketOrder != nil {
+		return dto.TicketOrderResponseSave{}, errTicketOrder
+	}
+
+	// an unset UserID keeps the order's owner, admins update orders of other users without naming them
+	if ticketOrder.TicketID != foundTicketOrder.TicketID || (ticketOrder.UserID != 0 && ticketOrder.UserID != foundTicketOrder.UserID) {
+		return dto.TicketOrderResponseSave{}, &domain.ValidationError{Message: "only the amount of a ticket order can be changed"}
+	}
+
+	unitPrice := foundTicketOrder.TotalPrice / float64(foundTicketOrder.Amount)
+	difference := ticketOrder.Amount - foundTicketOrder.Amount
+	logger.Debug().Msg(fmt.Sprintf("Ticket order amount changes by %d", difference))
+
+	if difference > 0 {
+		logger.Info().Msg("Attempting to deduct the extra tickets and charge the user")
+		_, errDeduct := uc.useCaseTicket.Deduct(ctx, foundTicketOrder.TicketID, difference)
+
+		if errDeduct != nil {
+			return dto.TicketOrderResponseSave{}, errDeduct
+		}
+
+		_, errBalance := uc.useCaseUser.ReduceBalance(ctx, foundTicketOrder.UserID, float64(difference) * unitPrice)
+
+		if errBalance != nil {
+			logger.Info().Msg("Charging the user failed, giving the deducted tickets back")
+			uc.giveBack(ctx, foundTicketOrder.TicketID, difference, foundTicketOrder.UserID, 0)
+			return dto.TicketOrderResponseSave{}, errBalance
+		}
+	} else if difference < 0 {
+		logger.Info().Msg("Attempting to restore the returned tickets and refund the user")
+		_, errRestore := uc.useCaseTicket.Restore(ctx, foundTicketOrder.TicketID, -difference)
+
+		if errRestore != nil {
+			return dto.TicketOrderResponseSave{}, errRestore
+		}
+
+		_, errBalance := uc.useCaseUser.AddBalance(ctx, foundTicketOrder.UserID, float64(-difference) * unitPrice)
+
+		if errBalance != nil {
+			logger.Info().Msg("Refunding the user failed, taking the restored tickets again")
+			uc.takeBack(ctx, foundTicketOrder.TicketID, -difference, foundTicketOrder.UserID, 0)
+			return dto.TicketOrderResponseSave{}, errBalance
+		}
+	}
+
+	foundTicketOrder.Amount = ticketOrder.Amount
+	foundTicketOrder.TotalPrice = float64(ticketOrder.Amount) * unitPrice
+
+	logger.Info().Msg("Attempting to call ticket order repo update")
+	errUpdate := uc.repoTicketOrder.Update(ctx, &foundTicketOrder)
+
+	if errUpdate != nil {
+		if difference > 0 {
+			logger.Info().Msg("Updating the order failed, giving the extra tickets back and refunding the user")
+			uc.giveBack(ctx, foundTicketOrder.TicketID, difference, foundTicketOrder.UserID, float64(difference) * unitPrice)
+		} else if difference < 0 {
+			logger.Info().Msg("Updating the order failed, taking the returned tickets and the refund again")
+			uc.takeBack(ctx, foundTicketOrder.TicketID, -difference, foundTicketOrder.UserID, float64(-difference) * unitPrice)
+		}
+		return dto.TicketOrderResponseSave{}, errUpdate
+	}
+
+	foundTicket, errTicket := uc.useCaseTicket.FindById(ctx, foundTicketOrder.TicketID)
+
+	if errTicket != nil {
+		return dto.TicketOrderResponseSave{}, errTicket
+	}
+
+	foundUser, errUser := uc.useCaseUser.FindById(ctx, foundTicketOrder.UserID)
+
+	if errUser != nil {
+		return dto.TicketOrderResponseSave{}, errUser
+	}
+
+	ticketOrderResponse := dto.TicketOrderResponseSave {
+		OrderID: foundTicketOrder.OrderID,
+		TicketDetails: foundTicket,
+		UserDetails: dto.UserResponse{
+			UserID: foundUser.UserID,
+			Email: foundUser.Email,
+			Name: foundUser.Name,
+			PhoneNumber: foundUser.PhoneNumber,
+			RemainingBalance: foundUser.Balance,
+		},
+		Amount: foundTicketOrder.Amount,
+		TotalPrice: foundTicketOrder.TotalPrice,
+	}
+	return ticketOrderResponse, nil
+}
+
+func (uc TicketOrderUseCase) Patch(ctx context.Context, id int, patch []byte) (dto.TicketOrderResponseSave, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering ticket order usecase patch")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.Patch", attribute.Int("order_id", id))
+	defer span.End()
+	foundTicketOrder, err := uc.findOwned(ctx, id)
+
+	if err != nil {
+		return dto.TicketOrderResponseSave{}, err
+	}
+
+	patchedTicketOrder, err := applyPatch(foundTicketOrder, patch)
+
+	if err != nil {
+		return dto.TicketOrderResponseSave{}, err
+	}
+
+	return uc.Update(ctx, id, patchedTicketOrder)
+}
+
+func (uc TicketOrderUseCase) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket order usecase delete")
+	ctx, span := tracing.Start(ctx, "TicketOrderUseCase.Delete", attribute.Int("order_id", id))
+	defer span.End()
+	logger.Info().Msg("Attempting to call ticket order repo to check if ticket order exist")
+	foundTicketOrder, errTicketOrder := uc.findOwned(ctx, id)
+
+	if errTicketOrder != nil {
+		return errTicketOrder
+	}
+
+	logger.Info().Msg("Attempting to restore the tickets and refund the user")
+	_, errRestore := uc.useCaseTicket.Restore(ctx, foundTicketOrder.TicketID, foundTicketOrder.Amount)
+
+	if errRestore != nil {
+		return errRestore
+	}
+
+	_, errBalance := uc.useCaseUser.AddBalance(ctx, foundTicketOrder.UserID, foundTicketOrder.TotalPrice)
+
+	if errBalance != nil {
+		logger.Info().Msg("Refunding the user failed, taking the restored tickets again")
+		uc.takeBack(ctx, foundTicketOrder.TicketID, foundTicketOrder.Amount, foundTicketOrder.UserID, 0)
+		return errBalance
+	}
+
+	errDelete := uc.repoTicketOrder.Delete(ctx, id)
+
+	if errDelete != nil {
+		logger.Info().Msg("Deleting the order failed, taking the restored tickets and the refund again")
+		uc.takeBack(ctx, foundTicketOrder.TicketID, foundTicketOrder.Amount, foundTicketOrder.UserID, foundTicketOrder.TotalPrice)
+		return errDelete
+	}
+
+	return nil
+}
+
+// giveBack gives back the tickets and refunds the balance taken for an order change that could not be saved.
+// It runs even when ctx was cancelled, stock taken by a failed order would otherwise never be sold again.
+func (uc TicketOrderUseCase) giveBack(ctx context.Context, ticketID int, amount int, userID int, refund float64) {
+	logger := zerolog.Ctx(ctx)
+	ctx = context.WithoutCancel(ctx)
+
+	if _, err := uc.useCaseTicket.Restore(ctx, ticketID, amount); err != nil {
+		logger.Error().Msg(fmt.Sprintf("Giving back %d tickets of ticket with ID %d failed: %s", amount, ticketID, err))
+	}
+
+	if refund == 0 {
+		return
+	}
+
+	if _, err := uc.useCaseUser.AddBalance(ctx, userID, refund); err != nil {
+		logger.Error().Msg(fmt.Sprintf("Refunding %.2f to user with ID %d failed: %s", refund, userID, err))
+	}
+}
+
+// takeBack takes again the tickets and the refund returned for an order change that could not be saved,
+// the opposite of giveBack, and like it runs even when ctx was cancelled
+func (uc TicketOrderUseCase) takeBack(ctx context.Context, ticketID int, amount int, userID int, charge float64) {
+	logger := zerolog.Ctx(ctx)
+	ctx = context.WithoutCancel(ctx)
+
+	if _, err := uc.useCaseTicket.Deduct(ctx, ticketID, amount); err != nil {
+		logger.Error().Msg(fmt.Sprintf("Taking back %d tickets of ticket with ID %d failed: %s", amount, ticketID, err))
+	}
+
+	if charge == 0 {
+		return
+	}
+
+	if _, err := uc.useCaseUser.ReduceBalance(ctx, userID, charge); err != nil {
+		logger.Error().Msg(fmt.Sprintf("Charging back %.2f to user with ID %d failed: %s", charge, userID, err))
+	}
+}
+
+// findOwned fetches a ticket order, reporting orders the caller may not see as not found
+func (uc TicketOrderUseCase) findOwned(ctx context.Context, id int) (domain.TicketOrder, error) {
+	foundTicketOrder, err := uc.repoTicketOrder.FindByID(ctx, id)
+
+	if err != nil {
+		return domain.TicketOrder{}, err
+	}
+
+	if !canSeeOrder(ctx, foundTicketOrder) {
+		zerolog.Ctx(ctx).Warn().Msg(fmt.Sprintf("Ticket order %d of user with ID %d is hidden from the caller", id, foundTicketOrder.UserID))
+		return domain.TicketOrder{}, &domain.NotFoundError{Entity: "Ticket order", ID: id}
+	}
+
+	return foundTicketOrder, nil
+}
+
+// canSeeOrder lets customers see only their own orders, admins and gate staff checking tickets see all of them
+func canSeeOrder(ctx context.Context, ticketOrder domain.TicketOrder) bool {
+	caller, ok := domain.CallerFromContext(ctx)
+	return !ok || ticketOrder.UserID == caller.UserID || caller.Is(domain.RoleAdmin, domain.RoleGateStaff)
+}
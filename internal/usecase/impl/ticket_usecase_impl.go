@@ -9,27 +9,30 @@ import (
 	"ticket_goroutine/internal/repository"
 	"ticket_goroutine/internal/usecase"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
 )
 
 type TicketUseCase struct {
 	repoTicket repository.TicketRepositoryInterface
 	useCaseEvent usecase.EventUseCaseInterface
+	repoTicketOrder repository.TicketOrderRepositoryInterface
 }
 
-func NewTicketUseCase(repoTicket repository.TicketRepositoryInterface, ucEvent usecase.EventUseCaseInterface) (usecase.TicketUseCaseInterface) {
+func NewTicketUseCase(repoTicket repository.TicketRepositoryInterface, ucEvent usecase.EventUseCaseInterface, repoTicketOrder repository.TicketOrderRepositoryInterface) (usecase.TicketUseCaseInterface) {
 	return TicketUseCase{
 		repoTicket: repoTicket,
 		useCaseEvent: ucEvent,
+		repoTicketOrder: repoTicketOrder,
 	}
 }
 
 func (uc TicketUseCase) Save(ctx context.Context, ticket domain.Ticket) (dto.TicketResponse, error) {
-	log.Trace().Msg("Entering ticket usecase save")
-	log.Info().Msg("Attempting to check event if exist")
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase save")
+	logger.Info().Msg("Attempting to check event if exist")
 
 	if uc.useCaseEvent == nil {
-        log.Error().Msg("useCaseEvent is nil")
+        logger.Error().Msg("useCaseEvent is nil")
         return dto.TicketResponse{}, errors.New("useCaseEvent is not initialized")
     }
 
@@ -39,7 +42,11 @@ func (uc TicketUseCase) Save(ctx context.Context, ticket domain.Ticket) (dto.Tic
 		return dto.TicketResponse{}, errEvent
 	}
 
-	log.Info().Msg("Attempting to call ticket repo save")
+	if err := checkEventOwner(ctx, foundEvent); err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	logger.Info().Msg("Attempting to call ticket repo save")
 	errSaved := uc.repoTicket.Save(ctx, &ticket)
 
 	if errSaved != nil {
@@ -61,15 +68,16 @@ func (uc TicketUseCase) Save(ctx context.Context, ticket domain.Ticket) (dto.Tic
 }
 
 func (uc TicketUseCase) FindById(ctx context.Context, id int) (dto.TicketResponse, error) {
-	log.Trace().Msg("Entering ticket usecase find by id")
-	log.Info().Msg("Attempting to call ticket repo to check if ticket exist")
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase find by id")
+	logger.Info().Msg("Attempting to call ticket repo to check if ticket exist")
 	foundTicket, errTicket := uc.repoTicket.FindByID(ctx, id)
 
 	if errTicket != nil {
 		return dto.TicketResponse{}, errTicket
 	}
 	
-	log.Info().Msg("Attempting to call event repo to check if event exist")
+	logger.Info().Msg("Attempting to call event repo to check if event exist")
 	foundEvent, errEvent := uc.useCaseEvent.FindById(ctx, foundTicket.EventID)
 
 	if errEvent != nil {
@@ -91,8 +99,9 @@ func (uc TicketUseCase) FindById(ctx context.Context, id int) (dto.TicketRespons
 }
 
 func (uc TicketUseCase) GetAll(ctx context.Context) ([]dto.TicketResponse, error) {
-	log.Trace().Msg("Entering ticket usecase get all")
-	log.Info().Msg("Attempting to call fetch all ticket")
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase get all")
+	logger.Info().Msg("Attempting to call fetch all ticket")
 	listOfTicket, err := uc.repoTicket.GetAll(ctx)
 
 	if err != nil {
@@ -126,19 +135,135 @@ func (uc TicketUseCase) GetAll(ctx context.Context) ([]dto.TicketResponse, error
 }
 
 func (uc TicketUseCase) Deduct(ctx context.Context, id int, amount int) (domain.Ticket, error) {
-	log.Trace().Msg("Entering ticket usecase deduct")
-	log.Info().Msg("Attempting to call ticket repo to deduct stock")
-	ticket, errTicket := uc.repoTicket.Deduct(ctx, id, amount)
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase deduct")
+	logger.Info().Msg("Attempting to call ticket repo to deduct stock")
+	ticket, errTicket := retryOnConflict(ctx, "Ticket deduct", func() (domain.Ticket, error) {
+		return uc.repoTicket.Deduct(ctx, id, amount)
+	})
 
 	if errTicket != nil {
-		return domain.Ticket{}, fmt.Errorf("")
+		return domain.Ticket{}, errTicket
 	}
 
 	return ticket, nil
 }
 
-// func (uc TicketUseCase) Restore(ctx context.Context, id int, amount int) {
-// 	log.Trace().Msg("Entering ticket usecase restore")
-// 	log.Info().Msg("Attempting to call ticket repo to restore stock")
-// 	uc.repoTicket.Restore(ctx, id, amount)
-// }
+func (uc TicketUseCase) Restore(ctx context.Context, id int, amount int) (domain.Ticket, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase restore")
+	logger.Info().Msg("Attempting to call ticket repo to restore stock")
+	return retryOnConflict(ctx, "Ticket restore", func() (domain.Ticket, error) {
+		return uc.repoTicket.Restore(ctx, id, amount)
+	})
+}
+
+func (uc TicketUseCase) Update(ctx context.Context, id int, ticket domain.Ticket) (dto.TicketResponse, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase update")
+	ticket.TicketID = id
+
+	logger.Info().Msg("Attempting to call ticket repo to check if ticket exist")
+	foundTicket, err := uc.repoTicket.FindByID(ctx, id)
+
+	if err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	if err := uc.checkTicketOwner(ctx, foundTicket); err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	logger.Info().Msg("Attempting to check event if exist")
+	foundEvent, errEvent := uc.useCaseEvent.FindById(ctx, ticket.EventID)
+
+	if errEvent != nil {
+		return dto.TicketResponse{}, errEvent
+	}
+
+	// moving a ticket needs ownership of the event it moves to as well
+	if err := checkEventOwner(ctx, foundEvent); err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	if ticket.Version == 0 {
+		logger.Info().Msg("No version given, updating against the current version")
+		ticket.Version = foundTicket.Version
+	}
+
+	logger.Info().Msg("Attempting to call ticket repo update")
+	errUpdate := uc.repoTicket.Update(ctx, &ticket)
+
+	if errUpdate != nil {
+		return dto.TicketResponse{}, errUpdate
+	}
+
+	ticketResponse := dto.TicketResponse {
+		TicketID: ticket.TicketID,
+		EventDetails: dto.EventResponse{
+			EventID: foundEvent.EventID,
+			EventName: foundEvent.EventName,
+		},
+		Name: ticket.Name,
+		Price: ticket.Price,
+		Stock: ticket.Stock,
+		Type: ticket.Type,
+	}
+	return ticketResponse, nil
+}
+
+func (uc TicketUseCase) Patch(ctx context.Context, id int, patch []byte) (dto.TicketResponse, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering ticket usecase patch")
+	foundTicket, err := uc.repoTicket.FindByID(ctx, id)
+
+	if err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	patchedTicket, err := applyPatch(foundTicket, patch)
+
+	if err != nil {
+		return dto.TicketResponse{}, err
+	}
+
+	return uc.Update(ctx, id, patchedTicket)
+}
+
+func (uc TicketUseCase) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering ticket usecase delete")
+	logger.Info().Msg("Attempting to call ticket repo to check if ticket exist")
+	foundTicket, err := uc.repoTicket.FindByID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := uc.checkTicketOwner(ctx, foundTicket); err != nil {
+		return err
+	}
+
+	logger.Info().Msg("Attempting to check if ticket has paid orders")
+	count, err := uc.repoTicketOrder.CountByTicketID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("ticket with ID %d %w and cannot be deleted", id, domain.ErrHasPaidOrders)
+	}
+
+	return uc.repoTicket.Delete(ctx, id)
+}
+
+// checkTicketOwner checks the caller owns the event the ticket is currently sold for
+func (uc TicketUseCase) checkTicketOwner(ctx context.Context, ticket domain.Ticket) error {
+	foundEvent, err := uc.useCaseEvent.FindById(ctx, ticket.EventID)
+
+	if err != nil {
+		return err
+	}
+
+	return checkEventOwner(ctx, foundEvent)
+}
@@ -2,40 +2,174 @@ package impl
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"ticket_goroutine/internal/domain"
 	"ticket_goroutine/internal/repository"
 	"ticket_goroutine/internal/usecase"
 
-	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserUseCase struct {
 	repo repository.UserRepositoryInterface
+	repoTicketOrder repository.TicketOrderRepositoryInterface
 }
 
-func NewUserUseCase(repo repository.UserRepositoryInterface) (usecase.UserUseCaseInterface) {
+func NewUserUseCase(repo repository.UserRepositoryInterface, repoTicketOrder repository.TicketOrderRepositoryInterface) (usecase.UserUseCaseInterface) {
 	return UserUseCase{
 		repo: repo,
+		repoTicketOrder: repoTicketOrder,
 	}
 }
 
 func (uc UserUseCase) Save(ctx context.Context, user domain.User) (domain.User, error) {
-	log.Trace().Msg("Entering user usecase save")
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase save")
+
+	if user.Password == "" {
+		return domain.User{}, &domain.ValidationError{
+			Message: "input didn't pass the validation",
+			Fields:  map[string]string{"Password": "Password is a required field"},
+		}
+	}
+
+	if user.Role == "" {
+		user.Role = domain.RoleCustomer
+	}
+
+	if user.Role != domain.RoleCustomer && !isAdmin(ctx) {
+		return domain.User{}, fmt.Errorf("only an admin can create a user with the %s role: %w", user.Role, domain.ErrForbidden)
+	}
+
+	// users signing up start without money, like they can't hand it to themselves later
+	if user.Balance != 0 && !isAdmin(ctx) {
+		return domain.User{}, fmt.Errorf("only an admin can create a user with a balance: %w", domain.ErrForbidden)
+	}
+
+	if err := hashPassword(&user); err != nil {
+		return domain.User{}, err
+	}
+
 	err := uc.repo.Save(ctx, &user)
 	return user, err
 }
 
 func (uc UserUseCase) FindById(ctx context.Context, id int) (domain.User, error) {
-	log.Trace().Msg("Entering user usecase find by id")
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase find by id")
 	return uc.repo.FindByID(ctx, id)
 }
 
 func (uc UserUseCase) GetAll(ctx context.Context) ([]domain.User, error) {
-	log.Trace().Msg("Entering user usecase get all")
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase get all")
 	return uc.repo.GetAll(ctx)
 }
 
 func (uc UserUseCase) ReduceBalance(ctx context.Context, id int, amount float64) (domain.User, error) {
-	log.Trace().Msg("Entering user usecase reduce balance")
-	return uc.repo.ReduceBalance(ctx, id, amount)
-}
\ No newline at end of file
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase reduce balance")
+	return retryOnConflict(ctx, "User reduce balance", func() (domain.User, error) {
+		return uc.repo.ReduceBalance(ctx, id, amount)
+	})
+}
+
+func (uc UserUseCase) AddBalance(ctx context.Context, id int, amount float64) (domain.User, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase add balance")
+	return retryOnConflict(ctx, "User add balance", func() (domain.User, error) {
+		return uc.repo.AddBalance(ctx, id, amount)
+	})
+}
+
+func (uc UserUseCase) Update(ctx context.Context, id int, user domain.User) (domain.User, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering user usecase update")
+	user.UserID = id
+
+	logger.Info().Msg("Attempting to call user repo to check if user exist")
+	foundUser, err := uc.repo.FindByID(ctx, id)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	if user.Version == 0 {
+		logger.Info().Msg("No version given, updating against the current version")
+		user.Version = foundUser.Version
+	}
+
+	if user.Role == "" {
+		user.Role = foundUser.Role
+	}
+
+	// users may edit their own profile, but not hand themselves a role or money
+	if (user.Role != foundUser.Role || user.Balance != foundUser.Balance) && !isAdmin(ctx) {
+		return domain.User{}, fmt.Errorf("only an admin can change the role or balance of a user: %w", domain.ErrForbidden)
+	}
+
+	// without a new password the repository keeps the stored hash
+	if err := hashPassword(&user); err != nil {
+		return domain.User{}, err
+	}
+
+	err = uc.repo.Update(ctx, &user)
+	return user, err
+}
+
+func (uc UserUseCase) Patch(ctx context.Context, id int, patch []byte) (domain.User, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering user usecase patch")
+	foundUser, err := uc.repo.FindByID(ctx, id)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	patchedUser, err := applyPatch(foundUser, patch)
+
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return uc.Update(ctx, id, patchedUser)
+}
+
+func (uc UserUseCase) Delete(ctx context.Context, id int) (error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Trace().Msg("Entering user usecase delete")
+	logger.Info().Msg("Attempting to check if user has paid orders")
+	count, err := uc.repoTicketOrder.CountByUserID(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("user with ID %d %w and cannot be deleted", id, domain.ErrHasPaidOrders)
+	}
+
+	return uc.repo.Delete(ctx, id)
+}
+
+// isAdmin reports whether the request was made by an admin
+func isAdmin(ctx context.Context) bool {
+	caller, ok := domain.CallerFromContext(ctx)
+	return ok && caller.Is(domain.RoleAdmin)
+}
+
+// hashPassword replaces the plain password of user with its bcrypt hash, it does nothing when no password was given
+func hashPassword(user *domain.User) error {
+	if user.Password == "" {
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return &domain.ValidationError{Message: "password is too long", Fields: map[string]string{"Password": "Password must be at most 72 bytes"}}
+		}
+		return err
+	}
+
+	user.PasswordHash = string(hash)
+	user.Password = ""
+	return nil
+}
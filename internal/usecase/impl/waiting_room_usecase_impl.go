@@ -0,0 +1,75 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+	"ticket_goroutine/internal/provider/waitingroom"
+	"ticket_goroutine/internal/usecase"
+
+	"github.com/rs/zerolog"
+)
+
+type WaitingRoomUseCase struct {
+	room *waitingroom.WaitingRoom
+}
+
+func NewWaitingRoomUseCase(room *waitingroom.WaitingRoom) (usecase.WaitingRoomUseCaseInterface) {
+	return WaitingRoomUseCase{
+		room: room,
+	}
+}
+
+func (uc WaitingRoomUseCase) Join(ctx context.Context) (dto.QueuePositionResponse, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering waiting room usecase join")
+	caller, ok := domain.CallerFromContext(ctx)
+
+	if !ok {
+		return dto.QueuePositionResponse{}, fmt.Errorf("only logged in users can queue: %w", domain.ErrUnauthorized)
+	}
+
+	position, err := uc.room.Join(caller.UserID)
+
+	if err != nil {
+		return dto.QueuePositionResponse{}, err
+	}
+
+	return queuePositionResponse(position), nil
+}
+
+func (uc WaitingRoomUseCase) Position(ctx context.Context, token string) (dto.QueuePositionResponse, error) {
+	zerolog.Ctx(ctx).Trace().Msg("Entering waiting room usecase position")
+	caller, ok := domain.CallerFromContext(ctx)
+
+	if !ok {
+		return dto.QueuePositionResponse{}, fmt.Errorf("only logged in users can queue: %w", domain.ErrUnauthorized)
+	}
+
+	position, err := uc.room.Position(token, caller.UserID)
+
+	if err != nil {
+		if errors.Is(err, waitingroom.ErrUnknownToken) {
+			return dto.QueuePositionResponse{}, fmt.Errorf("%w, join the waiting room again: %w", err, domain.ErrNotFound)
+		}
+		return dto.QueuePositionResponse{}, err
+	}
+
+	return queuePositionResponse(position), nil
+}
+
+func queuePositionResponse(position waitingroom.Position) dto.QueuePositionResponse {
+	response := dto.QueuePositionResponse{
+		QueueToken:           position.Token,
+		Position:             position.Place,
+		Admitted:             position.Admitted,
+		EstimatedWaitSeconds: int(position.EstimatedWait.Seconds()),
+	}
+
+	if position.Admitted {
+		response.AdmittedUntil = &position.AdmittedUntil
+	}
+
+	return response
+}
@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+	"ticket_goroutine/internal/domain"
+	"ticket_goroutine/internal/domain/dto"
+)
+
+type TicketOrderIntakeInterface interface {
+	TicketOrderIntakeSubmit
+	TicketOrderIntakeWait
+	TicketOrderIntakeStatus
+}
+
+type TicketOrderIntakeSubmit interface {
+	Submit(context context.Context, ticketOrder domain.TicketOrder) (dto.OrderRequestResponse, error)
+}
+
+type TicketOrderIntakeWait interface {
+	Wait(context context.Context, requestID string) (dto.OrderRequestResponse, error)
+}
+
+type TicketOrderIntakeStatus interface {
+	Status(context context.Context, requestID string) (dto.OrderRequestResponse, error)
+}
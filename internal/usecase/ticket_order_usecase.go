@@ -10,6 +10,10 @@ type TicketOrderUseCaseInterface interface {
 	TicketOrderSave
 	TicketOrderFindById
 	TicketOrderGetAll
+	TicketOrderGetByOrganizer
+	TicketOrderUpdate
+	TicketOrderPatch
+	TicketOrderDelete
 }
 
 type TicketOrderSave interface {
@@ -22,4 +26,20 @@ type TicketOrderFindById interface {
 
 type TicketOrderGetAll interface {
 	GetAll(context context.Context) ([]dto.TicketOrderResponse, error)
+}
+
+type TicketOrderGetByOrganizer interface {
+	GetByOrganizer(context context.Context, organizerID int) ([]dto.TicketOrderResponse, error)
+}
+
+type TicketOrderUpdate interface {
+	Update(context context.Context, id int, ticketOrder domain.TicketOrder) (dto.TicketOrderResponseSave, error)
+}
+
+type TicketOrderPatch interface {
+	Patch(context context.Context, id int, patch []byte) (dto.TicketOrderResponseSave, error)
+}
+
+type TicketOrderDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
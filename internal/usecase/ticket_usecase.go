@@ -11,7 +11,10 @@ type TicketUseCaseInterface interface {
 	TicketFindById
 	TicketGetAll
 	TicketDeduct
-	// TicketRestore
+	TicketRestore
+	TicketUpdate
+	TicketPatch
+	TicketDelete
 }
 
 type TicketSave interface {
@@ -31,5 +34,17 @@ type TicketDeduct interface {
 }
 
 type TicketRestore interface {
-	Restore(context context.Context, id int, amount int)
+	Restore(context context.Context, id int, amount int) (domain.Ticket, error)
+}
+
+type TicketUpdate interface {
+	Update(context context.Context, id int, ticket domain.Ticket) (dto.TicketResponse, error)
+}
+
+type TicketPatch interface {
+	Patch(context context.Context, id int, patch []byte) (dto.TicketResponse, error)
+}
+
+type TicketDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
@@ -10,6 +10,10 @@ type UserUseCaseInterface interface {
 	UserFindById
 	UserGetAll
 	UserBalanceReduce
+	UserBalanceAdd
+	UserUpdate
+	UserPatch
+	UserDelete
 }
 
 type UserSave interface {
@@ -26,4 +30,20 @@ type UserGetAll interface {
 
 type UserBalanceReduce interface {
 	ReduceBalance(context context.Context, id int, amount float64) (domain.User, error)
+}
+
+type UserBalanceAdd interface {
+	AddBalance(context context.Context, id int, amount float64) (domain.User, error)
+}
+
+type UserUpdate interface {
+	Update(context context.Context, id int, user domain.User) (domain.User, error)
+}
+
+type UserPatch interface {
+	Patch(context context.Context, id int, patch []byte) (domain.User, error)
+}
+
+type UserDelete interface {
+	Delete(context context.Context, id int) (error)
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"context"
+	"ticket_goroutine/internal/domain/dto"
+)
+
+type WaitingRoomUseCaseInterface interface {
+	WaitingRoomJoin
+	WaitingRoomPosition
+}
+
+type WaitingRoomJoin interface {
+	Join(context context.Context) (dto.QueuePositionResponse, error)
+}
+
+type WaitingRoomPosition interface {
+	Position(context context.Context, token string) (dto.QueuePositionResponse, error)
+}
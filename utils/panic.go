@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// panics counts the panics recovered since the process started
+var panics atomic.Int64
+
+// PanicError is a recovered panic, with the stack of the goroutine that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Log logs the panic with its stack and the request it happened while serving, through the request's logger
+func (e *PanicError) Log(r *http.Request) {
+	zerolog.Ctx(r.Context()).Error().
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int64("panics", PanicCount()).
+		Str("stack", string(e.Stack)).
+		Msg(e.Error())
+}
+
+// Recovered turns what recover returned into a *PanicError and counts it, it must be called from the deferred
+// function of the goroutine that panicked for the stack to show where
+func Recovered(recovered any) *PanicError {
+	panics.Add(1)
+	return &PanicError{Value: recovered, Stack: debug.Stack()}
+}
+
+// PanicCount is how many panics were recovered since the process started
+func PanicCount() int64 {
+	return panics.Load()
+}
+
+// Go runs fn on its own goroutine, handing a panic to onPanic instead of letting it crash the process
+func Go(fn func(), onPanic func(err *PanicError)) {
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				onPanic(Recovered(recovered))
+			}
+		}()
+
+		fn()
+	}()
+}
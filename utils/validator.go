@@ -1,17 +1,138 @@
 package utils
 
-import "github.com/go-playground/validator/v10"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"ticket_goroutine/internal/domain"
 
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	idTranslations "github.com/go-playground/validator/v10/translations/id"
+)
+
+// Validate is shared by every caller, validator caches struct metadata so it must not be rebuilt per call
 var Validate *validator.Validate
 
-func ValidateStruct(entity interface{}) error {
+var translator *ut.UniversalTranslator
+
+// customMessages are the en and id messages of the rules registered here, {0} is the field and {1} the rule parameter
+var customMessages = map[string]map[string]string{
+	"ticket_type": {
+		"en": "{0} must be one of " + strings.Join(domain.TicketTypes, ", "),
+		"id": "{0} harus salah satu dari " + strings.Join(domain.TicketTypes, ", "),
+	},
+	"phone_region": {
+		"en": "{0} must be a phone number with country code +{1}",
+		"id": "{0} harus berupa nomor telepon dengan kode negara +{1}",
+	},
+}
+
+func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
 
+	// report fields by their JSON name, which is what clients send
+	Validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+
+		if name == "-" {
+			return ""
+		}
+
+		if name == "" {
+			return field.Name
+		}
+
+		return name
+	})
+
+	Validate.RegisterValidation("ticket_type", func(fl validator.FieldLevel) bool {
+		return slices.Contains(domain.TicketTypes, fl.Field().String())
+	})
+
+	// phone_region=62 accepts only E.164 numbers of that country calling code
+	Validate.RegisterValidation("phone_region", func(fl validator.FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "+"+fl.Param())
+	})
+
+	english := en.New()
+	translator = ut.New(english, english, id.New())
+
+	mustRegister(enTranslations.RegisterDefaultTranslations(Validate, translatorFor("en")))
+	mustRegister(idTranslations.RegisterDefaultTranslations(Validate, translatorFor("id")))
+
+	for tag, messages := range customMessages {
+		for locale, message := range messages {
+			registerMessage(tag, translatorFor(locale), message)
+		}
+	}
+}
+
+func mustRegister(err error) {
+	if err != nil {
+		panic(fmt.Sprintf("registering validation messages failed: %s", err))
+	}
+}
+
+func registerMessage(tag string, trans ut.Translator, message string) {
+	register := func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}
+
+	translate := func(trans ut.Translator, fieldErr validator.FieldError) string {
+		translated, _ := trans.T(tag, fieldErr.Field(), fieldErr.Param())
+		return translated
+	}
+
+	mustRegister(Validate.RegisterTranslation(tag, trans, register, translate))
+}
+
+func translatorFor(locale string) ut.Translator {
+	trans, _ := translator.GetTranslator(locale)
+	return trans
+}
+
+// ValidateStruct returns a *domain.ValidationError with an English message per failed field, or the validator's own error when it could not run
+func ValidateStruct(entity interface{}) error {
 	err := Validate.Struct(entity)
 
 	if err != nil {
-		return err	
+		var validationErrors validator.ValidationErrors
+
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+
+		return &domain.ValidationError{
+			Message: "input didn't pass the validation",
+			Fields:  TranslateValidation(validationErrors, "en"),
+			Cause:   validationErrors,
+		}
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
+
+// TranslateValidation renders the field errors in the first language of an Accept-Language header we have messages for, English otherwise
+func TranslateValidation(validationErrors validator.ValidationErrors, acceptLanguage string) map[string]string {
+	var locales []string
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		locales = append(locales, strings.ToLower(strings.SplitN(tag, "-", 2)[0]))
+	}
+
+	trans, _ := translator.FindTranslator(locales...)
+
+	fields := make(map[string]string)
+	for _, fieldErr := range validationErrors {
+		fields[fieldErr.Field()] = fieldErr.Translate(trans)
+	}
+
+	return fields
+}